@@ -2,32 +2,175 @@ package kafka
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"sync"
+	"time"
 
 	"github.com/Shopify/sarama"
-	"github.com/mantzas/patron/async"
-	"github.com/mantzas/patron/encoding"
-	"github.com/mantzas/patron/log"
-	"github.com/mantzas/patron/trace"
-	"github.com/pkg/errors"
+	"github.com/beatlabs/patron/async"
+	"github.com/beatlabs/patron/encoding"
+	"github.com/beatlabs/patron/log"
 )
 
-// Component implementation of a kafka consumer.
+// CommitMode controls when Component commits a claimed message's offset
+// back to the broker.
+type CommitMode int
+
+const (
+	// CommitAuto marks the message and leaves the actual commit to sarama's
+	// own auto-commit ticker (config.Consumer.Offsets.AutoCommit). This is
+	// the default.
+	CommitAuto CommitMode = iota
+	// CommitAfterProcess marks the message and synchronously commits its
+	// offset as soon as proc returns nil, before claiming the next message.
+	CommitAfterProcess
+	// CommitBatch marks the message and commits once every commitBatchSize
+	// successfully-processed messages, or commitBatchInterval, whichever
+	// comes first. Configure via WithCommitBatch.
+	CommitBatch
+)
+
+// StartOffset selects where a Component with no previously committed offset
+// starts consuming a partition from.
+type StartOffset int
+
+const (
+	// OffsetOldest starts from the oldest available message. This is the default.
+	OffsetOldest StartOffset = iota
+	// OffsetNewest starts from the next message produced after the group joins.
+	OffsetNewest
+)
+
+// RebalanceStrategy selects the partition-assignment strategy the consumer
+// group uses when it rebalances.
+type RebalanceStrategy int
+
+const (
+	// RebalanceRange assigns contiguous partition ranges to group members. This is sarama's default.
+	RebalanceRange RebalanceStrategy = iota
+	// RebalanceRoundRobin distributes partitions round-robin across group members.
+	RebalanceRoundRobin
+	// RebalanceSticky minimizes partition movement across rebalances, at the cost of a slower join.
+	RebalanceSticky
+)
+
+// Component implementation of a kafka consumer, built on sarama's
+// consumer-group session/handler API so that scaling out replicas splits
+// the topic's partitions across them instead of every replica re-consuming
+// every partition from the oldest offset.
 type Component struct {
 	name        string
 	proc        async.ProcessorFunc
 	brokers     []string
 	topic       string
+	group       string
 	buffer      int
 	cfg         *sarama.Config
 	contentType string
+	decoder     encoding.DecodeRawFunc
+
+	commitMode          CommitMode
+	commitBatchSize     int
+	commitBatchInterval time.Duration
+
 	sync.Mutex
-	ms sarama.Consumer
+	cg     sarama.ConsumerGroup
+	cancel context.CancelFunc
+	failCh chan error
 }
 
-// New returns a new kafka consumer component.
-func New(name string, p async.ProcessorFunc, clientID, ct string, brokers []string, topic string,
-	buffer int) (*Component, error) {
+// ComponentOptionFunc defines an option function for New.
+type ComponentOptionFunc func(*Component) error
+
+// WithCommitAfterProcess sets CommitMode to CommitAfterProcess.
+func WithCommitAfterProcess() ComponentOptionFunc {
+	return func(c *Component) error {
+		c.commitMode = CommitAfterProcess
+		return nil
+	}
+}
+
+// WithCommitBatch sets CommitMode to CommitBatch, committing once every n
+// successfully-processed messages or interval, whichever comes first.
+func WithCommitBatch(n int, interval time.Duration) ComponentOptionFunc {
+	return func(c *Component) error {
+		if n <= 0 {
+			return errors.New("commit batch size must be greater than 0")
+		}
+		if interval <= 0 {
+			return errors.New("commit batch interval must be greater than 0")
+		}
+		c.commitMode = CommitBatch
+		c.commitBatchSize = n
+		c.commitBatchInterval = interval
+		return nil
+	}
+}
+
+// WithStartOffset sets where a Component with no previously committed
+// offset starts consuming from. It defaults to OffsetOldest.
+func WithStartOffset(o StartOffset) ComponentOptionFunc {
+	return func(c *Component) error {
+		switch o {
+		case OffsetOldest:
+			c.cfg.Consumer.Offsets.Initial = sarama.OffsetOldest
+		case OffsetNewest:
+			c.cfg.Consumer.Offsets.Initial = sarama.OffsetNewest
+		default:
+			return errors.New("unknown start offset")
+		}
+		return nil
+	}
+}
+
+// WithRebalanceStrategy sets the group's partition-assignment strategy. It
+// defaults to RebalanceRange.
+func WithRebalanceStrategy(s RebalanceStrategy) ComponentOptionFunc {
+	return func(c *Component) error {
+		switch s {
+		case RebalanceRange:
+			c.cfg.Consumer.Group.Rebalance.Strategy = sarama.BalanceStrategyRange
+		case RebalanceRoundRobin:
+			c.cfg.Consumer.Group.Rebalance.Strategy = sarama.BalanceStrategyRoundRobin
+		case RebalanceSticky:
+			c.cfg.Consumer.Group.Rebalance.Strategy = sarama.BalanceStrategySticky
+		default:
+			return errors.New("unknown rebalance strategy")
+		}
+		return nil
+	}
+}
+
+// WithSessionTimeout sets how long the group coordinator waits without a
+// heartbeat before considering this consumer dead and triggering a rebalance.
+func WithSessionTimeout(d time.Duration) ComponentOptionFunc {
+	return func(c *Component) error {
+		if d <= 0 {
+			return errors.New("session timeout must be greater than 0")
+		}
+		c.cfg.Consumer.Group.Session.Timeout = d
+		return nil
+	}
+}
+
+// WithHeartbeatInterval sets how often the consumer sends a heartbeat to
+// the group coordinator.
+func WithHeartbeatInterval(d time.Duration) ComponentOptionFunc {
+	return func(c *Component) error {
+		if d <= 0 {
+			return errors.New("heartbeat interval must be greater than 0")
+		}
+		c.cfg.Consumer.Group.Heartbeat.Interval = d
+		return nil
+	}
+}
+
+// New returns a new kafka consumer-group component, consuming topic as part
+// of group. ct, if not empty, pins every message to that content type
+// instead of determining it per-message from the content-type header.
+func New(name string, p async.ProcessorFunc, clientID, ct string, brokers []string, topic, group string,
+	buffer int, oo ...ComponentOptionFunc) (*Component, error) {
 	if name == "" {
 		return nil, errors.New("name is required")
 	}
@@ -48,6 +191,10 @@ func New(name string, p async.ProcessorFunc, clientID, ct string, brokers []stri
 		return nil, errors.New("topic is required")
 	}
 
+	if group == "" {
+		return nil, errors.New("group id is required")
+	}
+
 	if buffer < 0 {
 		return nil, errors.New("buffer must greater or equal than 0")
 	}
@@ -55,141 +202,168 @@ func New(name string, p async.ProcessorFunc, clientID, ct string, brokers []stri
 	config := sarama.NewConfig()
 	config.ClientID = clientID
 	config.Consumer.Return.Errors = true
+	config.Consumer.Offsets.Initial = sarama.OffsetOldest
+	if buffer > 0 {
+		config.ChannelBufferSize = buffer
+	}
 
-	return &Component{
+	c := &Component{
 		name:        name,
 		proc:        p,
 		brokers:     brokers,
 		topic:       topic,
+		group:       group,
 		cfg:         config,
-		ms:          nil,
 		contentType: ct,
 		buffer:      buffer,
-	}, nil
+	}
+
+	for _, o := range oo {
+		if err := o(c); err != nil {
+			return nil, err
+		}
+	}
+
+	if ct != "" {
+		dec, err := async.DetermineDecoder(ct)
+		if err != nil {
+			return nil, fmt.Errorf("failed to determine decoder for content type %s: %w", ct, err)
+		}
+		c.decoder = dec
+	}
+
+	return c, nil
 }
 
-// Run starts the kafka consumer processing messages.
+// Run starts the consumer-group session, dispatching claimed messages to
+// proc through ConsumeClaim until ctx is cancelled or a processing error
+// ends the session.
 func (c *Component) Run(ctx context.Context) error {
-
-	ms, err := sarama.NewConsumer(c.brokers, c.cfg)
+	cg, err := sarama.NewConsumerGroup(c.brokers, c.group, c.cfg)
 	if err != nil {
-		return errors.Wrap(err, "failed to create consumer")
+		return fmt.Errorf("failed to create consumer group: %w", err)
 	}
+
+	ctx, cancel := context.WithCancel(ctx)
+
 	c.Lock()
-	c.ms = ms
+	c.cg = cg
+	c.cancel = cancel
+	c.failCh = make(chan error, 1)
 	c.Unlock()
 
-	chMsg, chErr, err := c.consumers(ctx)
-	if err != nil {
-		return errors.Wrap(err, "failed to get consumers")
-	}
+	go func() {
+		for cgErr := range cg.Errors() {
+			log.Errorf("kafka consumer group error: %v", cgErr)
+		}
+	}()
 
-	failCh := make(chan error)
 	go func() {
 		for {
-			select {
-			case <-ctx.Done():
-				failCh <- errors.Wrap(c.ms.Close(), "failed to close consumer")
+			if err := cg.Consume(ctx, []string{c.topic}, c); err != nil {
+				select {
+				case c.failCh <- fmt.Errorf("consumer group session ended: %w", err):
+				default:
+				}
 				return
-			case msg := <-chMsg:
-				log.Debugf("data received from topic %s", msg.Topic)
-				go func() {
-					sp, chCtx := trace.StartConsumerSpan(ctx, c.name, trace.KafkaConsumerComponent,
-						mapHeader(msg.Headers))
-
-					var ct string
-					if c.contentType != "" {
-						ct = c.contentType
-					} else {
-						ct, err = determineContentType(msg.Headers)
-						if err != nil {
-							failCh <- errors.Wrap(err, "failed to determine content type")
-							trace.FinishSpanWithError(sp)
-							return
-						}
-					}
-
-					dec, err := async.DetermineDecoder(ct)
-					if err != nil {
-						failCh <- errors.Wrapf(err, "failed to determine decoder for %s", ct)
-						trace.FinishSpanWithError(sp)
-						return
-					}
-
-					err = c.proc(chCtx, async.NewMessage(msg.Value, dec))
-					if err != nil {
-						failCh <- errors.Wrap(err, "failed to process message")
-						trace.FinishSpanWithError(sp)
-						return
-					}
-					trace.FinishSpanWithSuccess(sp)
-				}()
-			case errMsg := <-chErr:
-				failCh <- errors.Wrap(errMsg, "an error occurred during consumption")
+			}
+			if ctx.Err() != nil {
 				return
 			}
 		}
 	}()
 
-	return <-failCh
+	select {
+	case err := <-c.failCh:
+		cancel()
+		return err
+	case <-ctx.Done():
+		return nil
+	}
 }
 
-// Shutdown gracefully the component by closing the kafka consumer.
+// Shutdown cancels the active consumer-group session context, so sarama
+// leaves the group and any rebalance on the remaining members completes
+// cleanly, then closes the underlying client.
 func (c *Component) Shutdown(ctx context.Context) error {
 	c.Lock()
 	defer c.Unlock()
-	return errors.Wrap(c.ms.Close(), "failed to close consumer")
+
+	if c.cancel != nil {
+		c.cancel()
+	}
+	if c.cg == nil {
+		return nil
+	}
+	if err := c.cg.Close(); err != nil {
+		return fmt.Errorf("failed to close consumer group: %w", err)
+	}
+	return nil
 }
 
-func (c *Component) consumers(ctx context.Context) (chan *sarama.ConsumerMessage, chan *sarama.ConsumerError, error) {
-	chMsg := make(chan *sarama.ConsumerMessage, c.buffer)
-	chErr := make(chan *sarama.ConsumerError, c.buffer)
+// Setup implements sarama.ConsumerGroupHandler. It runs once per rebalance,
+// before ConsumeClaim is called for any of the session's claimed partitions.
+func (c *Component) Setup(sess sarama.ConsumerGroupSession) error {
+	log.Debugf("kafka consumer group %s: session started, claims: %v", c.group, sess.Claims())
+	return nil
+}
 
-	partitions, err := c.ms.Partitions(c.topic)
-	if err != nil {
-		return nil, nil, errors.Wrap(err, "failed to get partitions")
-	}
+// Cleanup implements sarama.ConsumerGroupHandler. It runs once per
+// rebalance, after every ConsumeClaim for the session has returned.
+func (c *Component) Cleanup(sess sarama.ConsumerGroupSession) error {
+	log.Debugf("kafka consumer group %s: session ended", c.group)
+	return nil
+}
 
-	for _, partition := range partitions {
+// ConsumeClaim implements sarama.ConsumerGroupHandler, routing every
+// message claimed for a single partition through proc. A message is only
+// marked, and its offset ever committed, once proc returns nil; on error it
+// surfaces on failCh and ends this claim, same as on the monolithic
+// per-partition consumer this replaces.
+func (c *Component) ConsumeClaim(sess sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	var sinceCommit int
+	lastCommit := time.Now()
 
-		pc, err := c.ms.ConsumePartition(c.topic, partition, sarama.OffsetOldest)
-		if nil != err {
-			return nil, nil, errors.Wrap(err, "failed to get partition consumer")
-		}
+	for {
+		select {
+		case <-sess.Context().Done():
+			return nil
+		case msg, ok := <-claim.Messages():
+			if !ok {
+				return nil
+			}
 
-		go func(consumer sarama.PartitionConsumer) {
-			for {
-				select {
-				case <-ctx.Done():
-					break
-				case consumerError := <-consumer.Errors():
-					chErr <- consumerError
+			am, err := ClaimMessage(sess.Context(), msg, c.decoder, sess)
+			if err != nil {
+				return fmt.Errorf("failed to claim message from topic %s: %w", msg.Topic, err)
+			}
+			m := am.(*message)
 
-				case msg := <-consumer.Messages():
-					chMsg <- msg
+			if procErr := c.proc(m.Context(), m); procErr != nil {
+				_ = m.Nack()
+				werr := fmt.Errorf("failed to process message from topic %s: %w", msg.Topic, procErr)
+				select {
+				case c.failCh <- werr:
+				default:
 				}
+				return werr
 			}
-		}(pc)
-	}
 
-	return chMsg, chErr, nil
-}
-
-func determineContentType(hdr []*sarama.RecordHeader) (string, error) {
+			if err := m.Ack(); err != nil {
+				return fmt.Errorf("failed to ack message from topic %s: %w", msg.Topic, err)
+			}
 
-	for _, h := range hdr {
-		if string(h.Key) == encoding.ContentTypeHeader {
-			return string(h.Value), nil
+			switch c.commitMode {
+			case CommitAfterProcess:
+				sess.Commit()
+			case CommitBatch:
+				sinceCommit++
+				if sinceCommit >= c.commitBatchSize || time.Since(lastCommit) >= c.commitBatchInterval {
+					sess.Commit()
+					sinceCommit = 0
+					lastCommit = time.Now()
+				}
+			}
 		}
 	}
-
-	return "", errors.New("content type header is missing")
 }
-
-func mapHeader(hh []*sarama.RecordHeader) map[string]string {
-	mp := make(map[string]string)
-	for _, h := range hh {
-		mp[string(h.Key)] = string(h.Value)
-	}
-	return mp
-}
\ No newline at end of file
@@ -1,41 +1,176 @@
 package lru
 
 import (
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/beatlabs/patron/log"
+	"github.com/beatlabs/patron/cache"
+	"github.com/beatlabs/patron/errors"
 	lru "github.com/hashicorp/golang-lru"
 )
 
-// Cache encapsulates a thread-safe fixed size LRU cache
-// as defined in hashicorp/golang-lru.
+const defaultSweepInterval = time.Minute
+
+// Sizer estimates the size in bytes of a cached value, used to bound the
+// cache by memory footprint via WithMaxBytes, independently of item count.
+type Sizer func(value interface{}) int
+
+// Stats reports point-in-time hit/miss/eviction counters and the current
+// size of a Cache.
+type Stats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Size      int
+	Bytes     int
+}
+
+// Option configures a Cache created via New.
+type Option func(*Cache) error
+
+// WithMaxBytes bounds the cache by total byte size, estimated via sizer, in
+// addition to the item-count limit passed to New. Once the byte budget is
+// exceeded, the least-recently-used items are evicted until it is honored
+// again.
+func WithMaxBytes(maxBytes int, sizer Sizer) Option {
+	return func(c *Cache) error {
+		if maxBytes <= 0 {
+			return errors.New("max bytes must be positive")
+		}
+		if sizer == nil {
+			return errors.New("sizer is required when setting max bytes")
+		}
+		c.maxBytes = maxBytes
+		c.sizer = sizer
+		return nil
+	}
+}
+
+// WithEvictCallback registers a callback invoked whenever a key is evicted
+// from the cache, whether due to capacity, an explicit Remove/Purge, or TTL
+// expiry.
+func WithEvictCallback(cb func(key, value interface{})) Option {
+	return func(c *Cache) error {
+		if cb == nil {
+			return errors.New("evict callback is nil")
+		}
+		c.onEvict = cb
+		return nil
+	}
+}
+
+// WithSweepInterval overrides the interval at which the cache sweeps for
+// keys past their TTL. It defaults to one minute.
+func WithSweepInterval(d time.Duration) Option {
+	return func(c *Cache) error {
+		if d <= 0 {
+			return errors.New("sweep interval must be positive")
+		}
+		c.sweepInterval = d
+		return nil
+	}
+}
+
+// Cache encapsulates a thread-safe fixed size LRU cache as defined in
+// hashicorp/golang-lru, optionally bounded by byte size and with TTL-based
+// expiry.
 type Cache struct {
-	lru *lru.Cache
+	// hits, misses and evictions are accessed atomically and must stay
+	// first so they remain 64-bit aligned on 32-bit platforms.
+	hits      uint64
+	misses    uint64
+	evictions uint64
+
+	lru           *lru.Cache
+	onEvict       func(key, value interface{})
+	maxBytes      int
+	sizer         Sizer
+	sweepInterval time.Duration
+	sweepOnce     sync.Once
+	sweepStop     chan struct{}
+	closeOnce     sync.Once
+
+	mu     sync.Mutex
+	bytes  int
+	sizes  map[string]int
+	expiry map[string]time.Time
 }
 
+var _ cache.TTLCache = (*Cache)(nil)
+
 // Create returns a new LRU cache.
+//
+// Deprecated: use New, which additionally accepts Option configuration.
 func Create(size int) (*Cache, error) {
-	lruCache, err := lru.New(size)
+	return New(size)
+}
+
+// New returns a new LRU cache bounded by size items, configured by opts.
+func New(size int, opts ...Option) (*Cache, error) {
+	c := &Cache{
+		expiry:        make(map[string]time.Time),
+		sizes:         make(map[string]int),
+		sweepInterval: defaultSweepInterval,
+		sweepStop:     make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, err
+		}
+	}
+
+	lruCache, err := lru.NewWithEvict(size, c.handleEviction)
 	if err != nil {
 		return nil, err
 	}
-	return &Cache{lru: lruCache}, nil
+	c.lru = lruCache
+
+	return c, nil
+}
+
+// Close stops the cache's background TTL sweeper, if it was ever started by
+// a call to SetTTL. It must be called once a cache using SetTTL is no
+// longer in use, to avoid leaking the sweeper goroutine.
+func (c *Cache) Close() error {
+	c.closeOnce.Do(func() { close(c.sweepStop) })
+	return nil
 }
 
 // Contains returns whether the key exists in cache, without updating its recent-ness.
 func (c *Cache) Contains(key string) (bool, error) {
+	if c.isExpired(key) {
+		return false, nil
+	}
 	return c.lru.Contains(key), nil
 }
 
 // Get executes a lookup and returns whether a key exists in the cache along with and its value.
 func (c *Cache) Get(key string) (interface{}, bool, error) {
+	if c.isExpired(key) {
+		_ = c.Remove(key)
+		atomic.AddUint64(&c.misses, 1)
+		return nil, false, nil
+	}
+
 	value, ok := c.lru.Get(key)
+	if ok {
+		atomic.AddUint64(&c.hits, 1)
+	} else {
+		atomic.AddUint64(&c.misses, 1)
+	}
 	return value, ok, nil
 }
 
-// Purge evicts all keys present in the cache.
+// Purge evicts all keys present in the cache, one at a time via Remove, so
+// that byte accounting, TTL bookkeeping and the evict callback stay
+// consistent regardless of whether the underlying LRU implementation's bulk
+// purge invokes its eviction hook.
 func (c *Cache) Purge() error {
-	c.lru.Purge()
+	for _, k := range c.lru.Keys() {
+		c.lru.Remove(k)
+	}
 
 	return nil
 }
@@ -49,19 +184,125 @@ func (c *Cache) Remove(key string) error {
 
 // Set registers a key-value pair to the cache.
 func (c *Cache) Set(key string, value interface{}) error {
-	c.lru.Add(key, value)
+	c.mu.Lock()
+	delete(c.expiry, key)
+	c.mu.Unlock()
 
-	return nil
+	return c.add(key, value)
 }
 
-// SetTTL registers a key-value pair to the cache. Once the provided duration expires,
-// the function will try to erase the key from the cache.
+// SetTTL registers a key-value pair to the cache. Once the provided duration
+// expires, the key is removed lazily on the next Get, or eagerly by the
+// background sweeper, which is started on the first call to SetTTL.
 func (c *Cache) SetTTL(key string, value interface{}, ttl time.Duration) error {
-	c.lru.Add(key, value)
-	time.AfterFunc(ttl, func() {
-		err := c.Remove(key)
-		log.Fatalf("failed to remove key from golang-lru cache after its ttl has expired : %v", err)
-	})
+	c.sweepOnce.Do(func() { go c.sweep() })
+
+	if err := c.add(key, value); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.expiry[key] = time.Now().Add(ttl)
+	c.mu.Unlock()
 
 	return nil
 }
+
+// Stats returns a snapshot of the cache's hit/miss/eviction counters and size.
+func (c *Cache) Stats() Stats {
+	c.mu.Lock()
+	bytes := c.bytes
+	c.mu.Unlock()
+
+	return Stats{
+		Hits:      atomic.LoadUint64(&c.hits),
+		Misses:    atomic.LoadUint64(&c.misses),
+		Evictions: atomic.LoadUint64(&c.evictions),
+		Size:      c.lru.Len(),
+		Bytes:     bytes,
+	}
+}
+
+func (c *Cache) add(key string, value interface{}) error {
+	if c.sizer != nil {
+		size := c.sizer(value)
+		c.mu.Lock()
+		c.bytes += size - c.sizes[key]
+		c.sizes[key] = size
+		c.mu.Unlock()
+	}
+
+	c.lru.Add(key, value)
+	c.enforceMaxBytes()
+	return nil
+}
+
+func (c *Cache) enforceMaxBytes() {
+	if c.maxBytes <= 0 {
+		return
+	}
+	for {
+		c.mu.Lock()
+		over := c.bytes > c.maxBytes
+		c.mu.Unlock()
+		if !over || c.lru.Len() == 0 {
+			return
+		}
+		c.lru.RemoveOldest()
+	}
+}
+
+func (c *Cache) isExpired(key string) bool {
+	c.mu.Lock()
+	exp, ok := c.expiry[key]
+	c.mu.Unlock()
+	return ok && time.Now().After(exp)
+}
+
+func (c *Cache) handleEviction(key, value interface{}) {
+	atomic.AddUint64(&c.evictions, 1)
+
+	if k, ok := key.(string); ok {
+		c.mu.Lock()
+		if c.sizer != nil {
+			c.bytes -= c.sizes[k]
+			delete(c.sizes, k)
+		}
+		delete(c.expiry, k)
+		c.mu.Unlock()
+	}
+
+	if c.onEvict != nil {
+		c.onEvict(key, value)
+	}
+}
+
+func (c *Cache) sweep() {
+	t := time.NewTicker(c.sweepInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			c.sweepExpired()
+		case <-c.sweepStop:
+			return
+		}
+	}
+}
+
+func (c *Cache) sweepExpired() {
+	now := time.Now()
+
+	c.mu.Lock()
+	var expired []string
+	for k, exp := range c.expiry {
+		if now.After(exp) {
+			expired = append(expired, k)
+		}
+	}
+	c.mu.Unlock()
+
+	for _, k := range expired {
+		_ = c.Remove(k)
+	}
+}
@@ -2,6 +2,7 @@ package lru
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -63,3 +64,107 @@ func TestCacheOperations(t *testing.T) {
 		assert.Equal(t, c.lru.Len(), 0)
 	})
 }
+
+func TestCache_Stats(t *testing.T) {
+	c, err := New(10)
+	assert.NoError(t, err)
+
+	_, _, _ = c.Get("missing")
+	assert.NoError(t, c.Set("foo", "bar"))
+	_, _, _ = c.Get("foo")
+
+	s := c.Stats()
+	assert.Equal(t, uint64(1), s.Hits)
+	assert.Equal(t, uint64(1), s.Misses)
+	assert.Equal(t, 1, s.Size)
+}
+
+func TestCache_OnEvict(t *testing.T) {
+	var evicted []string
+	c, err := New(1, WithEvictCallback(func(key, _ interface{}) {
+		evicted = append(evicted, key.(string))
+	}))
+	assert.NoError(t, err)
+
+	assert.NoError(t, c.Set("key1", "val1"))
+	assert.NoError(t, c.Set("key2", "val2"))
+
+	assert.Equal(t, []string{"key1"}, evicted)
+	assert.Equal(t, uint64(1), c.Stats().Evictions)
+}
+
+func TestCache_WithMaxBytes(t *testing.T) {
+	sizer := func(v interface{}) int { return len(v.(string)) }
+	c, err := New(10, WithMaxBytes(5, sizer))
+	assert.NoError(t, err)
+
+	assert.NoError(t, c.Set("key1", "12345"))
+	assert.NoError(t, c.Set("key2", "12345"))
+
+	ok, err := c.Contains("key1")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	ok, err = c.Contains("key2")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestCache_SetTTL_Sweep(t *testing.T) {
+	c, err := New(10, WithSweepInterval(10*time.Millisecond))
+	assert.NoError(t, err)
+	defer func() { assert.NoError(t, c.Close()) }()
+
+	assert.NoError(t, c.SetTTL("foo", "bar", 5*time.Millisecond))
+
+	assert.Eventually(t, func() bool {
+		ok, _ := c.Contains("foo")
+		return !ok
+	}, 200*time.Millisecond, 10*time.Millisecond)
+}
+
+func TestCache_Contains_ExpiredBeforeSweep(t *testing.T) {
+	c, err := New(10, WithSweepInterval(time.Hour))
+	assert.NoError(t, err)
+	defer func() { assert.NoError(t, c.Close()) }()
+
+	assert.NoError(t, c.SetTTL("foo", "bar", 5*time.Millisecond))
+	time.Sleep(10 * time.Millisecond)
+
+	ok, err := c.Contains("foo")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestCache_Close_Idempotent(t *testing.T) {
+	c, err := New(10)
+	assert.NoError(t, err)
+	assert.NoError(t, c.SetTTL("foo", "bar", time.Hour))
+	assert.NoError(t, c.Close())
+	assert.NoError(t, c.Close())
+}
+
+func TestCache_Purge_ResetsByteAccounting(t *testing.T) {
+	sizer := func(v interface{}) int { return len(v.(string)) }
+	c, err := New(10, WithMaxBytes(5, sizer))
+	assert.NoError(t, err)
+
+	assert.NoError(t, c.Set("key1", "12345"))
+	assert.NoError(t, c.Purge())
+	assert.NoError(t, c.Set("key2", "12345"))
+
+	ok, err := c.Contains("key2")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestCache_InvalidOptions(t *testing.T) {
+	_, err := New(10, WithMaxBytes(0, nil))
+	assert.Error(t, err)
+
+	_, err = New(10, WithEvictCallback(nil))
+	assert.Error(t, err)
+
+	_, err = New(10, WithSweepInterval(0))
+	assert.Error(t, err)
+}
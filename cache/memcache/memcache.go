@@ -0,0 +1,81 @@
+// Package memcache provides a cache.TTLCache implementation backed by
+// Memcached, driven by bradfitz/gomemcache.
+package memcache
+
+import (
+	"time"
+
+	"github.com/beatlabs/patron/cache"
+	"github.com/beatlabs/patron/errors"
+	"github.com/bradfitz/gomemcache/memcache"
+)
+
+// Cache encapsulates a Memcached-based caching mechanism,
+// driven by bradfitz/gomemcache.
+type Cache struct {
+	mc *memcache.Client
+}
+
+var _ cache.TTLCache = (*Cache)(nil)
+
+// New returns a new Memcached client, connected to the given servers, that
+// will be used as the cache store.
+func New(servers ...string) (*Cache, error) {
+	if len(servers) == 0 {
+		return nil, errors.New("at least one server is required")
+	}
+	return &Cache{mc: memcache.New(servers...)}, nil
+}
+
+// Contains returns whether the key exists in the cache.
+func (c *Cache) Contains(key string) (bool, error) {
+	_, ok, err := c.Get(key)
+	return ok, err
+}
+
+// Get executes a lookup and returns whether a key exists in the cache along with and its value.
+func (c *Cache) Get(key string) (interface{}, bool, error) {
+	item, err := c.mc.Get(key)
+	if err == memcache.ErrCacheMiss {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return string(item.Value), true, nil
+}
+
+// Set registers a key-value pair to the cache. value must be a string, since
+// Memcached stores raw bytes.
+func (c *Cache) Set(key string, value interface{}) error {
+	v, ok := value.(string)
+	if !ok {
+		return errors.New("value must be a string")
+	}
+	return c.mc.Set(&memcache.Item{Key: key, Value: []byte(v)})
+}
+
+// Purge evicts all keys present in the cache.
+func (c *Cache) Purge() error {
+	return c.mc.FlushAll()
+}
+
+// Remove evicts a specific key from the cache.
+func (c *Cache) Remove(key string) error {
+	err := c.mc.Delete(key)
+	if err == memcache.ErrCacheMiss {
+		return nil
+	}
+	return err
+}
+
+// SetTTL registers a key-value pair to the cache. Once the provided duration
+// expires, Memcached erases the key itself. value must be a string, since
+// Memcached stores raw bytes.
+func (c *Cache) SetTTL(key string, value interface{}, ttl time.Duration) error {
+	v, ok := value.(string)
+	if !ok {
+		return errors.New("value must be a string")
+	}
+	return c.mc.Set(&memcache.Item{Key: key, Value: []byte(v), Expiration: int32(ttl.Seconds())})
+}
@@ -0,0 +1,30 @@
+package memcache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew_Invalid(t *testing.T) {
+	c, err := New()
+	assert.Nil(t, c)
+	assert.EqualError(t, err, "at least one server is required")
+}
+
+func TestSet_InvalidValue(t *testing.T) {
+	c, err := New("localhost:11211")
+	assert.NoError(t, err)
+
+	err = c.Set("key", 42)
+	assert.EqualError(t, err, "value must be a string")
+}
+
+func TestSetTTL_InvalidValue(t *testing.T) {
+	c, err := New("localhost:11211")
+	assert.NoError(t, err)
+
+	err = c.SetTTL("key", 42, time.Second)
+	assert.EqualError(t, err, "value must be a string")
+}
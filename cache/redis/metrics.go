@@ -0,0 +1,17 @@
+package redis
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var commandDuration *prometheus.HistogramVec
+
+func init() {
+	commandDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "cache",
+			Subsystem: "redis",
+			Name:      "command_duration_seconds",
+			Help:      "Redis command latency in seconds, classified by command",
+		}, []string{"command"},
+	)
+	prometheus.MustRegister(commandDuration)
+}
@@ -1,6 +1,9 @@
 package redis
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 type kv struct {
 	value string
@@ -12,6 +15,8 @@ type MockClient struct {
 	data map[string]kv
 }
 
+var _ Client = (*MockClient)(nil)
+
 // NewMockRedis initializes the MockClient.
 func NewMockRedis() *MockClient {
 	return &MockClient{make(map[string]kv)}
@@ -52,3 +57,39 @@ func (m *MockClient) SetTTL(key string, value interface{}, ttl time.Duration) er
 	m.data[key] = kv{value.(string), ttl}
 	return nil
 }
+
+// ContainsCtx performs a lookup on the mock client data. ctx is ignored, as
+// the mock has nothing to cancel or time out.
+func (m *MockClient) ContainsCtx(_ context.Context, key string) (bool, error) {
+	return m.Contains(key)
+}
+
+// GetCtx performs a lookup on the mock client data and returns the value.
+// ctx is ignored, as the mock has nothing to cancel or time out.
+func (m *MockClient) GetCtx(_ context.Context, key string) (interface{}, bool, error) {
+	return m.Get(key)
+}
+
+// SetCtx sets the value on a stored mock client entry. ctx is ignored, as
+// the mock has nothing to cancel or time out.
+func (m *MockClient) SetCtx(_ context.Context, key string, value interface{}) error {
+	return m.Set(key, value)
+}
+
+// SetTTLCtx sets the value on a stored mock client entry, also setting a TTL
+// parameter. ctx is ignored, as the mock has nothing to cancel or time out.
+func (m *MockClient) SetTTLCtx(_ context.Context, key string, value interface{}, ttl time.Duration) error {
+	return m.SetTTL(key, value, ttl)
+}
+
+// RemoveCtx deletes a key from the mock client data. ctx is ignored, as the
+// mock has nothing to cancel or time out.
+func (m *MockClient) RemoveCtx(_ context.Context, key string) error {
+	return m.Remove(key)
+}
+
+// PurgeCtx clears out the mock client data. ctx is ignored, as the mock has
+// nothing to cancel or time out.
+func (m *MockClient) PurgeCtx(_ context.Context) error {
+	return m.Purge()
+}
@@ -4,7 +4,10 @@ import (
 	"context"
 	"time"
 
+	"github.com/beatlabs/patron/cache"
+	"github.com/beatlabs/patron/trace"
 	"github.com/beatlabs/patron/trace/redis"
+	"github.com/opentracing/opentracing-go"
 )
 
 // Cache encapsulates a Redis-based caching mechanism,
@@ -14,19 +17,105 @@ type Cache struct {
 	ctx context.Context
 }
 
+var _ cache.TTLCache = (*Cache)(nil)
+var _ Client = (*Cache)(nil)
+
+// Client is the interface implemented by Cache and MockClient, letting
+// callers swap a real Redis-backed cache for an in-memory mock in tests
+// without depending on the concrete type. The *Ctx methods behave exactly
+// like their plain counterparts, but take a caller-supplied context.Context
+// instead of context.Background().
+type Client interface {
+	Contains(key string) (bool, error)
+	Get(key string) (interface{}, bool, error)
+	Set(key string, value interface{}) error
+	SetTTL(key string, value interface{}, ttl time.Duration) error
+	Remove(key string) error
+	Purge() error
+
+	ContainsCtx(ctx context.Context, key string) (bool, error)
+	GetCtx(ctx context.Context, key string) (interface{}, bool, error)
+	SetCtx(ctx context.Context, key string, value interface{}) error
+	SetTTLCtx(ctx context.Context, key string, value interface{}, ttl time.Duration) error
+	RemoveCtx(ctx context.Context, key string) error
+	PurgeCtx(ctx context.Context) error
+}
+
 // Options exposes the options struct from go-redis package
 type Options redis.Options
 
+// SentinelOptions exposes the Sentinel options struct from the trace/redis package.
+type SentinelOptions redis.SentinelOptions
+
+// ClusterOptions exposes the Cluster options struct from the trace/redis package.
+type ClusterOptions redis.ClusterOptions
+
 // New returns a new Redis client that will be used as the cache store.
 func New(opt Options) (*Cache, error) {
-	redisDB := redis.New(context.Background(), redis.Options(opt))
-	return &Cache{rdb: redisDB, ctx: context.Background()}, nil
+	return &Cache{rdb: redis.New(redis.Options(opt)), ctx: context.Background()}, nil
+}
+
+// NewSentinel returns a new Redis client, backed by a Sentinel-monitored
+// master/replica deployment, that will be used as the cache store.
+func NewSentinel(opt SentinelOptions) (*Cache, error) {
+	return &Cache{rdb: redis.NewSentinel(redis.SentinelOptions(opt)), ctx: context.Background()}, nil
+}
+
+// NewCluster returns a new Redis client, backed by a Redis Cluster deployment,
+// that will be used as the cache store.
+func NewCluster(opt ClusterOptions) (*Cache, error) {
+	return &Cache{rdb: redis.NewCluster(redis.ClusterOptions(opt)), ctx: context.Background()}, nil
+}
+
+// span starts a child span for a Redis command cmd, tagged the way an
+// OpenTelemetry-compatible consumer expects from a database client.
+func (c *Cache) span(ctx context.Context, cmd string) (opentracing.Span, context.Context) {
+	return trace.ChildSpan(ctx, trace.ComponentOpName("redis", cmd), "redis",
+		opentracing.Tag{Key: "db.system", Value: "redis"},
+		opentracing.Tag{Key: "db.statement", Value: cmd},
+		opentracing.Tag{Key: "net.peer.name", Value: c.rdb.Instance()},
+	)
+}
+
+// observe records cmd's latency since start and completes sp with err.
+func (c *Cache) observe(sp opentracing.Span, cmd string, start time.Time, err error) {
+	commandDuration.WithLabelValues(cmd).Observe(time.Since(start).Seconds())
+	trace.SpanComplete(sp, err)
+}
+
+// Contains returns whether the key exists in the cache.
+func (c *Cache) Contains(key string) (bool, error) {
+	return c.ContainsCtx(c.ctx, key)
+}
+
+// ContainsCtx returns whether the key exists in the cache.
+func (c *Cache) ContainsCtx(ctx context.Context, key string) (bool, error) {
+	start := time.Now()
+	sp, ctx := c.span(ctx, "exists")
+	n, err := c.rdb.Do(ctx, "exists", key).Int64()
+	c.observe(sp, "exists", start, err)
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
 }
 
 // Get executes a lookup and returns whether a key exists in the cache along with and its value.
 func (c *Cache) Get(key string) (interface{}, bool, error) {
-	res, err := c.rdb.Do(c.ctx, "get", key)
-	if err == redis.Empty || err != nil {
+	return c.GetCtx(c.ctx, key)
+}
+
+// GetCtx executes a lookup and returns whether a key exists in the cache along with and its value.
+func (c *Cache) GetCtx(ctx context.Context, key string) (interface{}, bool, error) {
+	start := time.Now()
+	sp, ctx := c.span(ctx, "get")
+	res, err := c.rdb.Do(ctx, "get", key).Result()
+	if err == redis.Nil {
+		c.observe(sp, "get", start, nil)
+		return nil, false, nil
+	}
+	c.observe(sp, "get", start, err)
+	if err != nil {
 		return nil, false, err
 	}
 	return res, true, nil
@@ -34,25 +123,67 @@ func (c *Cache) Get(key string) (interface{}, bool, error) {
 
 // Set registers a key-value pair to the cache.
 func (c *Cache) Set(key string, value interface{}) error {
-	_, err := c.rdb.Do(c.ctx, "set", key, value)
+	return c.SetCtx(c.ctx, key, value)
+}
+
+// SetCtx registers a key-value pair to the cache.
+func (c *Cache) SetCtx(ctx context.Context, key string, value interface{}) error {
+	start := time.Now()
+	sp, ctx := c.span(ctx, "set")
+	err := c.rdb.Do(ctx, "set", key, value).Err()
+	c.observe(sp, "set", start, err)
 	return err
 }
 
 // Purge evicts all keys present in the cache.
 func (c *Cache) Purge() error {
-	_, err := c.rdb.Do(c.ctx, "flushdb")
+	return c.PurgeCtx(c.ctx)
+}
+
+// PurgeCtx evicts all keys present in the cache.
+func (c *Cache) PurgeCtx(ctx context.Context) error {
+	start := time.Now()
+	sp, ctx := c.span(ctx, "flushdb")
+	err := c.rdb.Do(ctx, "flushdb").Err()
+	c.observe(sp, "flushdb", start, err)
 	return err
 }
 
 // Remove evicts a specific key from the cache.
 func (c *Cache) Remove(key string) error {
-	_, err := c.rdb.Do(c.ctx, "del", key)
+	return c.RemoveCtx(c.ctx, key)
+}
+
+// RemoveCtx evicts a specific key from the cache.
+func (c *Cache) RemoveCtx(ctx context.Context, key string) error {
+	start := time.Now()
+	sp, ctx := c.span(ctx, "del")
+	err := c.rdb.Do(ctx, "del", key).Err()
+	c.observe(sp, "del", start, err)
 	return err
 }
 
 // SetTTL registers a key-value pair to the cache. Once the provided duration expires,
 // the function will try to erase the key from the cache.
 func (c *Cache) SetTTL(key string, value interface{}, ttl time.Duration) error {
-	_, err := c.rdb.Do(c.ctx, "set", key, value, "px", int(ttl.Milliseconds()))
+	return c.SetTTLCtx(c.ctx, key, value, ttl)
+}
+
+// SetTTLCtx registers a key-value pair to the cache. Once the provided duration expires,
+// the function will try to erase the key from the cache.
+func (c *Cache) SetTTLCtx(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	start := time.Now()
+	sp, ctx := c.span(ctx, "set")
+	err := c.rdb.Do(ctx, "set", key, value, "px", int(ttl.Milliseconds())).Err()
+	c.observe(sp, "set", start, err)
+	return err
+}
+
+// HealthCheck pings the underlying Redis deployment, returning a non-nil
+// error if it is unreachable. It takes only a context and returns only an
+// error so it can be wired into the HTTP component's WithReadyCheckFunc by
+// wrapping it into that package's ReadyCheckFunc signature.
+func (c *Cache) HealthCheck(ctx context.Context) error {
+	_, err := c.rdb.Ping(ctx)
 	return err
 }
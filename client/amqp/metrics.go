@@ -0,0 +1,17 @@
+package amqp
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var countReconnects *prometheus.CounterVec
+
+func init() {
+	countReconnects = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "client",
+			Subsystem: "amqp_publisher",
+			Name:      "reconnects",
+			Help:      "Successful reconnects performed by a Publisher after its connection or channel was lost, classified by exchange",
+		}, []string{"exchange"},
+	)
+	prometheus.MustRegister(countReconnects)
+}
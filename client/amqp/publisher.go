@@ -0,0 +1,484 @@
+package amqp
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/beatlabs/patron/encoding/json"
+	"github.com/beatlabs/patron/errors"
+	"github.com/beatlabs/patron/log"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	defaultInitialBackoff = 500 * time.Millisecond
+	defaultMaxBackoff     = 30 * time.Second
+	publishRetryDelay     = 100 * time.Millisecond
+)
+
+// Message represents a message to be published.
+type Message struct {
+	body        []byte
+	contentType string
+}
+
+// NewMessage creates a new message with the given content type.
+func NewMessage(body []byte, contentType string) *Message {
+	return &Message{body: body, contentType: contentType}
+}
+
+// NewJSONMessage creates a new message, JSON encoding body.
+func NewJSONMessage(body string) (*Message, error) {
+	b, err := json.Encode(body)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode message")
+	}
+	return NewMessage(b, json.Type), nil
+}
+
+// OptionFunc configures a Publisher created via NewPublisher.
+type OptionFunc func(*Publisher) error
+
+// WithReconnect configures how the Publisher re-dials RabbitMQ after the
+// connection or channel is lost: up to maxAttempts times (0 means
+// unlimited), backing off exponentially from initialBackoff up to
+// maxBackoff, with jitter to avoid a thundering herd against the broker.
+func WithReconnect(maxAttempts int, initialBackoff, maxBackoff time.Duration) OptionFunc {
+	return func(p *Publisher) error {
+		if maxAttempts < 0 {
+			return errors.New("max attempts must be greater or equal than 0")
+		}
+		if initialBackoff <= 0 {
+			return errors.New("initial backoff must be positive")
+		}
+		if maxBackoff < initialBackoff {
+			return errors.New("max backoff must be greater or equal than initial backoff")
+		}
+		p.maxAttempts = maxAttempts
+		p.initialBackoff = initialBackoff
+		p.maxBackoff = maxBackoff
+		return nil
+	}
+}
+
+// WithConfirms puts the underlying channel into RabbitMQ publisher confirms
+// mode, so Publish blocks until the broker has acked (or nacked) the
+// message, instead of returning as soon as the frame has left the client. An
+// ack only means the broker accepted the message, not that it was routed to
+// a queue; combine with WithMandatory and NotifyReturn to detect an
+// unroutable message.
+func WithConfirms() OptionFunc {
+	return func(p *Publisher) error {
+		p.confirms = true
+		return nil
+	}
+}
+
+// WithMandatory marks every published message as mandatory, so the broker
+// sends it back instead of silently dropping it when no queue is bound to
+// match the routing key. Call NotifyReturn too to actually receive it; the
+// broker's return is otherwise discarded since nothing is listening for it.
+func WithMandatory() OptionFunc {
+	return func(p *Publisher) error {
+		p.mandatory = true
+		return nil
+	}
+}
+
+// WithPersistentDelivery marks every published message as persistent, so the
+// broker writes it to disk before acking it.
+func WithPersistentDelivery() OptionFunc {
+	return func(p *Publisher) error {
+		p.persistent = true
+		return nil
+	}
+}
+
+// Publisher is a RabbitMQ publisher that transparently re-dials the broker
+// whenever the underlying connection or channel is lost, instead of
+// surfacing a "channel/connection is not open" error to every caller.
+type Publisher struct {
+	url            string
+	exchange       string
+	maxAttempts    int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	confirms       bool
+	mandatory      bool
+	persistent     bool
+	closed         chan struct{}
+	closeSignal    sync.Once
+	closeResources sync.Once
+
+	mu      sync.RWMutex
+	conn    *amqp.Connection
+	ch      *amqp.Channel
+	ready   chan struct{}
+	returnC chan amqp.Return
+}
+
+// NewPublisher creates a new publisher for the given url and exchange.
+func NewPublisher(url, exchange string, oo ...OptionFunc) (*Publisher, error) {
+	if url == "" {
+		return nil, errors.New("url is required")
+	}
+	if exchange == "" {
+		return nil, errors.New("exchange is required")
+	}
+
+	p := &Publisher{
+		url:            url,
+		exchange:       exchange,
+		initialBackoff: defaultInitialBackoff,
+		maxBackoff:     defaultMaxBackoff,
+		closed:         make(chan struct{}),
+		ready:          make(chan struct{}),
+	}
+
+	for _, o := range oo {
+		if err := o(p); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := p.connect(); err != nil {
+		return nil, err
+	}
+
+	go p.supervise()
+
+	return p, nil
+}
+
+func (p *Publisher) connect() error {
+	conn, err := amqp.Dial(p.url)
+	if err != nil {
+		return errors.Wrap(err, "failed to open RabbitMq connection")
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		_ = conn.Close()
+		return errors.Wrap(err, "failed to open channel")
+	}
+
+	err = ch.ExchangeDeclare(p.exchange, amqp.ExchangeFanout, true, false, false, false, nil)
+	if err != nil {
+		_ = ch.Close()
+		_ = conn.Close()
+		return errors.Wrap(err, "failed to declare exchange")
+	}
+
+	if p.confirms {
+		if err := ch.Confirm(false); err != nil {
+			_ = ch.Close()
+			_ = conn.Close()
+			return errors.Wrap(err, "failed to enable publisher confirms")
+		}
+	}
+
+	// Check closed inside the same critical section that installs conn/ch, so
+	// a Close() racing with an in-flight reconnect can never be followed by a
+	// later connect() silently overwriting the connection it just closed.
+	p.mu.Lock()
+	select {
+	case <-p.closed:
+		p.mu.Unlock()
+		_ = ch.Close()
+		_ = conn.Close()
+		return errors.New("publisher is closed")
+	default:
+	}
+	oldConn := p.conn
+	p.conn = conn
+	p.ch = ch
+	// NotifyReturn is registered per-channel by the broker, so a channel
+	// previously supplied via NotifyReturn needs to be re-attached on every
+	// reconnect; doing it before close(p.ready), under the same lock as the
+	// assignments above, means no publish can reach the new channel before
+	// the registration is in place, and a concurrent NotifyReturn call is
+	// serialized against this one instead of racing it.
+	if p.returnC != nil {
+		p.registerReturn(ch, p.returnC)
+	}
+	close(p.ready)
+	p.mu.Unlock()
+
+	// The broker may close just the channel while leaving the connection up,
+	// in which case connect dials a fresh connection too; close the stale one
+	// here instead of leaking it.
+	if oldConn != nil {
+		_ = oldConn.Close()
+	}
+
+	return nil
+}
+
+// supervise watches the active channel's NotifyClose and re-dials the broker
+// whenever it fires, until the Publisher is closed or reconnect gives up
+// after exhausting WithReconnect's maxAttempts.
+func (p *Publisher) supervise() {
+	for {
+		p.mu.RLock()
+		ch := p.ch
+		p.mu.RUnlock()
+
+		notifyClose := ch.NotifyClose(make(chan *amqp.Error, 1))
+
+		select {
+		case <-p.closed:
+			return
+		case err := <-notifyClose:
+			if err != nil {
+				log.Errorf("amqp connection closed, reconnecting: %v", err)
+			}
+		}
+
+		// A select with both cases ready picks pseudo-randomly, so closed may
+		// have raced notifyClose above; re-check before resetting ready so a
+		// Close() that happens to close the channel too never leaves a fresh,
+		// non-closed ready behind.
+		select {
+		case <-p.closed:
+			return
+		default:
+		}
+
+		p.mu.Lock()
+		p.ready = make(chan struct{})
+		p.mu.Unlock()
+
+		if !p.reconnect() {
+			return
+		}
+	}
+}
+
+// reconnect retries connect with exponential backoff and jitter, up to
+// maxAttempts if set. It reports whether it reconnected successfully; false
+// means either the Publisher was closed or maxAttempts was exhausted, and
+// the caller should stop supervising.
+func (p *Publisher) reconnect() bool {
+	backoff := p.initialBackoff
+
+	for attempt := 1; ; attempt++ {
+		select {
+		case <-p.closed:
+			return false
+		default:
+		}
+
+		if err := p.connect(); err == nil {
+			countReconnects.WithLabelValues(p.exchange).Inc()
+			return true
+		} else {
+			log.Errorf("failed to reconnect to RabbitMq (attempt %d): %v", attempt, err)
+		}
+
+		if p.maxAttempts > 0 && attempt >= p.maxAttempts {
+			log.Errorf("giving up reconnecting to RabbitMq after %d attempts", attempt)
+			// The broker is permanently unreachable as far as this Publisher is
+			// concerned: mark it closed so callers blocked in waitReady fail fast
+			// instead of hanging until their own context expires.
+			p.markClosed()
+			return false
+		}
+
+		select {
+		case <-p.closed:
+			return false
+		case <-time.After(jitter(backoff)):
+		}
+
+		backoff *= 2
+		if backoff > p.maxBackoff {
+			backoff = p.maxBackoff
+		}
+	}
+}
+
+func jitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d)/2+1))
+}
+
+// NotifyReturn registers c to receive messages returned by the broker, e.g.
+// because WithMandatory was set and no queue matched the routing key. Unlike
+// (*amqp.Channel).NotifyReturn, c is never closed by the Publisher, and only
+// the most recently registered c is re-attached across reconnects, so call
+// it once per Publisher rather than to add further listeners: a previous c
+// from an earlier call stops receiving returns as soon as the connection is
+// lost and re-established.
+func (p *Publisher) NotifyReturn(c chan amqp.Return) chan amqp.Return {
+	// registerReturn is called while still holding the lock, in the same
+	// critical section as the p.returnC assignment, so this can never
+	// register c against a channel that a concurrent reconnect is about to
+	// retire in favour of one that only has the registration from connect().
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.returnC = c
+	if p.ch != nil {
+		p.registerReturn(p.ch, c)
+	}
+
+	return c
+}
+
+// registerReturn subscribes an internal, Publisher-owned channel to ch's
+// returns and relays them to out for as long as ch stays open, instead of
+// handing ch.NotifyReturn the caller's own channel directly. amqp091-go
+// closes every channel registered via NotifyReturn when the underlying AMQP
+// channel shuts down; since out is re-registered against a new channel on
+// every reconnect, that would otherwise close out itself the first time the
+// connection was lost, and this Publisher would then try to write to (and
+// panic on) an already-closed caller-owned channel after reconnecting.
+func (p *Publisher) registerReturn(ch *amqp.Channel, out chan amqp.Return) {
+	internal := ch.NotifyReturn(make(chan amqp.Return))
+	go func() {
+		for r := range internal {
+			out <- r
+		}
+	}()
+}
+
+// Publish publishes msg to the exchange. While the connection is being
+// re-established it blocks, bounded by ctx, instead of failing immediately.
+// If WithConfirms was set, Publish also blocks until the broker has acked
+// the message.
+func (p *Publisher) Publish(ctx context.Context, msg *Message) error {
+	if msg == nil {
+		return errors.New("message is required")
+	}
+
+	for {
+		if err := p.waitReady(ctx); err != nil {
+			return err
+		}
+
+		p.mu.RLock()
+		ch := p.ch
+		p.mu.RUnlock()
+
+		pub := amqp.Publishing{
+			ContentType: msg.contentType,
+			Body:        msg.body,
+		}
+		if p.persistent {
+			pub.DeliveryMode = amqp.Persistent
+		}
+
+		var err error
+		if p.confirms {
+			err = p.publishAndConfirm(ctx, ch, pub)
+		} else {
+			err = ch.PublishWithContext(ctx, p.exchange, "", p.mandatory, false, pub)
+			if err != nil {
+				err = errors.Wrap(err, "failed to publish message")
+			}
+		}
+		if err == nil {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(publishRetryDelay):
+		}
+	}
+}
+
+// publishAndConfirm publishes pub over ch and waits for its broker
+// confirmation. It relies on amqp091-go's own DeferredConfirmation to
+// correlate the confirmation with this specific publish by delivery tag, so
+// concurrent confirmed publishes on the same channel never read each other's
+// confirmation.
+//
+// If the broker's ack arrives at essentially the same instant ctx expires,
+// DeferredConfirmation.WaitContext may report the context error instead of
+// the ack, the same way any select racing a context deadline against a real
+// event can pick either case; callers that retry a "context cancelled"
+// confirmed Publish should be aware a very narrow window like this could
+// turn a successful publish into a duplicate.
+func (p *Publisher) publishAndConfirm(ctx context.Context, ch *amqp.Channel, pub amqp.Publishing) error {
+	dc, err := ch.PublishWithDeferredConfirmWithContext(ctx, p.exchange, "", p.mandatory, false, pub)
+	if err != nil {
+		return errors.Wrap(err, "failed to publish message")
+	}
+
+	ack, err := dc.WaitContext(ctx)
+	if err != nil {
+		return errors.Wrap(err, "context cancelled while waiting for publish confirmation")
+	}
+	if !ack {
+		// A dropped channel/connection resolves every pending confirmation as
+		// a non-error "not acked" too, indistinguishable from a real nack by
+		// ack alone; check whether the channel is still open to tell a
+		// transient disconnect (safe to retry) from a genuine broker nack.
+		if ch.IsClosed() {
+			return errors.New("channel closed while waiting for publish confirmation")
+		}
+		return errors.New("message was nacked by the broker")
+	}
+	return nil
+}
+
+func (p *Publisher) waitReady(ctx context.Context) error {
+	// Checked up front, and with priority over ready below: once closed, ready
+	// is never reset to a fresh channel, so without this a caller could keep
+	// racing the two cases and occasionally retry against a dead connection
+	// instead of failing promptly.
+	select {
+	case <-p.closed:
+		return errors.New("publisher is closed")
+	default:
+	}
+
+	p.mu.RLock()
+	ready := p.ready
+	p.mu.RUnlock()
+
+	select {
+	case <-ready:
+		return nil
+	case <-p.closed:
+		return errors.New("publisher is closed")
+	case <-ctx.Done():
+		return errors.Wrap(ctx.Err(), "context cancelled while waiting to publish")
+	}
+}
+
+func (p *Publisher) markClosed() {
+	p.closeSignal.Do(func() { close(p.closed) })
+}
+
+// Close closes the underlying connection and stops the reconnect supervisor.
+// It is idempotent: calling it more than once, or after the reconnect
+// supervisor has already given up following WithReconnect's maxAttempts,
+// simply returns nil once the underlying connection is no longer open.
+func (p *Publisher) Close(_ context.Context) error {
+	p.markClosed()
+
+	var err error
+	p.closeResources.Do(func() {
+		p.mu.RLock()
+		ch := p.ch
+		conn := p.conn
+		p.mu.RUnlock()
+
+		var ee []error
+		if cerr := ch.Close(); cerr != nil && cerr != amqp.ErrClosed {
+			ee = append(ee, cerr)
+		}
+		if cerr := conn.Close(); cerr != nil && cerr != amqp.ErrClosed {
+			ee = append(ee, cerr)
+		}
+		err = errors.Aggregate(ee...)
+	})
+	return err
+}
@@ -0,0 +1,26 @@
+package amqp
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var countRetries *prometheus.CounterVec
+var countDeadLettered *prometheus.CounterVec
+
+func init() {
+	countRetries = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "component",
+			Subsystem: "amqp_subscriber",
+			Name:      "message_retries",
+			Help:      "Deliveries republished for retry by a WithDeadLetter-configured Subscriber, classified by exchange",
+		}, []string{"exchange"},
+	)
+	countDeadLettered = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "component",
+			Subsystem: "amqp_subscriber",
+			Name:      "messages_dead_lettered",
+			Help:      "Deliveries that exceeded their retry budget and were dead-lettered by a WithDeadLetter-configured Subscriber, classified by exchange",
+		}, []string{"exchange"},
+	)
+	prometheus.MustRegister(countRetries, countDeadLettered)
+}
@@ -0,0 +1,281 @@
+package amqp
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/beatlabs/patron/errors"
+	"github.com/beatlabs/patron/log"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// retryCountHeader tracks how many times a delivery has been republished
+// for retry by a dead-letter-configured Subscriber.
+const retryCountHeader = "x-retry-count"
+
+const defaultRetryDelay = 5 * time.Second
+
+// DecodeRequestFunc decodes an incoming AMQP delivery into a domain request.
+type DecodeRequestFunc func(ctx context.Context, del *amqp.Delivery) (interface{}, error)
+
+// EncodeResponseFunc encodes a domain response onto an outgoing AMQP publishing.
+type EncodeResponseFunc func(ctx context.Context, pub *amqp.Publishing, response interface{}) error
+
+// EndpointFunc processes a request decoded by a DecodeRequestFunc and returns
+// a domain response.
+type EndpointFunc func(ctx context.Context, request interface{}) (interface{}, error)
+
+// ResponsePublisher publishes an encoded response, typically back to a
+// delivery's ReplyTo queue on the default exchange.
+type ResponsePublisher interface {
+	Publish(ctx context.Context, exchange, key string, pub amqp.Publishing) error
+}
+
+// Subscriber wires an EndpointFunc, a DecodeRequestFunc and, optionally, a
+// response encoder and publisher, so request/response handlers can be built
+// over RabbitMQ deliveries the same way patron routes are built over HTTP
+// requests, instead of hand-rolling decoding inside every message handler.
+type Subscriber struct {
+	ep  EndpointFunc
+	dec DecodeRequestFunc
+	enc EncodeResponseFunc
+	pub ResponsePublisher
+
+	dlExchange   string
+	dlRoutingKey string
+	dlMaxRetries int
+	dlRetryDelay time.Duration
+	dlPub        ResponsePublisher
+}
+
+// SubscriberOptionFunc defines an option function for NewSubscriber.
+type SubscriberOptionFunc func(*Subscriber) error
+
+// WithResponse configures the Subscriber to reply, RPC-style, to every
+// delivery that carries a ReplyTo queue: enc encodes the endpoint's response
+// and pub publishes it back, correlated via the delivery's CorrelationId.
+func WithResponse(enc EncodeResponseFunc, pub ResponsePublisher) SubscriberOptionFunc {
+	return func(s *Subscriber) error {
+		if enc == nil {
+			return errors.New("response encoder is required")
+		}
+		if pub == nil {
+			return errors.New("response publisher is required")
+		}
+		s.enc = enc
+		s.pub = pub
+		return nil
+	}
+}
+
+// WithDeadLetter configures the Subscriber to handle decode/endpoint
+// failures itself instead of leaving ack/nack to the caller: while a
+// delivery has been retried fewer than maxRetries times (tracked via the
+// x-retry-count header), ServeDelivery republishes it through pub to a
+// per-delivery retry exchange/routing key (the incoming exchange and
+// routing key, each suffixed ".retry") with a per-message TTL set via
+// WithRetryDelay, so it bounces back to its original queue once that
+// delay elapses, via DeclareDeadLetterTopology's retry queue. Once
+// maxRetries is exceeded, it is instead republished to exchange/routingKey,
+// the dead-letter destination. Either way, ServeDelivery then returns nil,
+// leaving del itself to be ack'd by the caller exactly as on success.
+//
+// A delivery's retry-vs-dead-letter destination can only be chosen by
+// publishing explicitly, not via Nack: a queue's own dead-letter-exchange
+// argument is fixed at declare time, so it cannot route the same queue's
+// messages to the retry exchange now and the final exchange later.
+func WithDeadLetter(exchange, routingKey string, maxRetries int, pub ResponsePublisher) SubscriberOptionFunc {
+	return func(s *Subscriber) error {
+		if exchange == "" {
+			return errors.New("dead-letter exchange is required")
+		}
+		if routingKey == "" {
+			return errors.New("dead-letter routing key is required")
+		}
+		if maxRetries < 0 {
+			return errors.New("max retries must be greater or equal than 0")
+		}
+		if pub == nil {
+			return errors.New("dead-letter publisher is required")
+		}
+		s.dlExchange = exchange
+		s.dlRoutingKey = routingKey
+		s.dlMaxRetries = maxRetries
+		s.dlPub = pub
+		if s.dlRetryDelay == 0 {
+			s.dlRetryDelay = defaultRetryDelay
+		}
+		return nil
+	}
+}
+
+// WithRetryDelay overrides the per-message TTL a WithDeadLetter-configured
+// Subscriber sets on a retried delivery, i.e. how long it waits in the
+// retry queue before bouncing back to the original queue. It defaults to
+// 5 seconds.
+func WithRetryDelay(d time.Duration) SubscriberOptionFunc {
+	return func(s *Subscriber) error {
+		if d <= 0 {
+			return errors.New("retry delay must be positive")
+		}
+		s.dlRetryDelay = d
+		return nil
+	}
+}
+
+// NewSubscriber creates a Subscriber that decodes deliveries with dec and
+// processes them with ep.
+func NewSubscriber(ep EndpointFunc, dec DecodeRequestFunc, oo ...SubscriberOptionFunc) (*Subscriber, error) {
+	if ep == nil {
+		return nil, errors.New("endpoint is required")
+	}
+	if dec == nil {
+		return nil, errors.New("decoder is required")
+	}
+
+	s := &Subscriber{ep: ep, dec: dec}
+
+	for _, o := range oo {
+		if err := o(s); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// ServeDelivery decodes del, invokes the endpoint and, if a response encoder
+// and publisher have been configured via WithResponse and del carries a
+// ReplyTo queue, publishes the encoded response back to it.
+//
+// If WithDeadLetter was configured, a decode or endpoint failure is handled
+// internally (see WithDeadLetter): a copy of del is republished to the retry
+// or dead-letter destination and ServeDelivery returns nil, same as on
+// success, so the caller always Acks del itself exactly as before; otherwise
+// the failure is returned as before, leaving ack/nack to the caller.
+func (s *Subscriber) ServeDelivery(ctx context.Context, del *amqp.Delivery) error {
+	req, err := s.dec(ctx, del)
+	if err != nil {
+		return s.handleFailure(ctx, del, errors.Wrap(err, "failed to decode delivery"))
+	}
+
+	resp, err := s.ep(ctx, req)
+	if err != nil {
+		return s.handleFailure(ctx, del, errors.Wrap(err, "endpoint failed to process request"))
+	}
+
+	if s.enc == nil || s.pub == nil || del.ReplyTo == "" {
+		return nil
+	}
+
+	pub := amqp.Publishing{CorrelationId: del.CorrelationId}
+	if err := s.enc(ctx, &pub, resp); err != nil {
+		return errors.Wrap(err, "failed to encode response")
+	}
+
+	return s.pub.Publish(ctx, "", del.ReplyTo, pub)
+}
+
+// handleFailure applies the configured dead-letter policy to a decode or
+// endpoint failure for del, by republishing a copy of it to the retry or
+// dead-letter destination. It never acks or nacks del itself, so the
+// caller's own ack/nack handling of del stays exactly as before, regardless
+// of whether WithDeadLetter is configured: it returns failureErr unchanged
+// if it is not, or nil once a copy has been safely republished.
+func (s *Subscriber) handleFailure(ctx context.Context, del *amqp.Delivery, failureErr error) error {
+	if s.dlExchange == "" {
+		return failureErr
+	}
+
+	headers := amqp.Table{}
+	for k, v := range del.Headers {
+		headers[k] = v
+	}
+
+	retries := retryCount(del.Headers)
+	if retries >= s.dlMaxRetries {
+		log.Errorf("dead-lettering delivery after %d retries: %v", retries, failureErr)
+		countDeadLettered.WithLabelValues(del.Exchange).Inc()
+		if err := s.dlPub.Publish(ctx, s.dlExchange, s.dlRoutingKey, amqp.Publishing{
+			Headers:       headers,
+			ContentType:   del.ContentType,
+			Body:          del.Body,
+			CorrelationId: del.CorrelationId,
+		}); err != nil {
+			return errors.Wrap(err, "failed to publish delivery to dead-letter exchange")
+		}
+		return nil
+	}
+
+	log.Errorf("retrying delivery (attempt %d/%d): %v", retries+1, s.dlMaxRetries, failureErr)
+	countRetries.WithLabelValues(del.Exchange).Inc()
+	headers[retryCountHeader] = retries + 1
+
+	if err := s.dlPub.Publish(ctx, del.Exchange+".retry", del.RoutingKey+".retry", amqp.Publishing{
+		Headers:       headers,
+		ContentType:   del.ContentType,
+		Body:          del.Body,
+		CorrelationId: del.CorrelationId,
+		Expiration:    strconv.FormatInt(s.dlRetryDelay.Milliseconds(), 10),
+	}); err != nil {
+		return errors.Wrap(err, "failed to republish delivery for retry")
+	}
+	return nil
+}
+
+// retryCount reads the retry-count header set by a previous republish of a
+// WithDeadLetter-configured Subscriber, defaulting to 0 if absent or of an
+// unexpected type.
+func retryCount(hh amqp.Table) int {
+	switch n := hh[retryCountHeader].(type) {
+	case int:
+		return n
+	case int32:
+		return int(n)
+	case int64:
+		return int(n)
+	default:
+		return 0
+	}
+}
+
+// DeclareDeadLetterTopology declares the exchanges and queues a
+// WithDeadLetter-configured Subscriber relies on, against mainExchange and
+// mainQueue (already bound with mainRoutingKey): a retry exchange/queue,
+// suffixed ".retry", that dead-letters back to mainExchange/mainRoutingKey
+// once a retried delivery's per-message TTL (WithRetryDelay) elapses, and
+// the final dlExchange/dlRoutingKey destination for deliveries that
+// exhausted their retries. Call it once per queue, during consumer
+// startup, alongside declaring mainQueue itself.
+func DeclareDeadLetterTopology(ch *amqp.Channel, mainExchange, mainQueue, mainRoutingKey, dlExchange, dlRoutingKey string) error {
+	retryExchange := mainExchange + ".retry"
+	retryQueue := mainQueue + ".retry"
+	retryRoutingKey := mainRoutingKey + ".retry"
+
+	if err := ch.ExchangeDeclare(retryExchange, amqp.ExchangeDirect, true, false, false, false, nil); err != nil {
+		return errors.Wrap(err, "failed to declare retry exchange")
+	}
+	if _, err := ch.QueueDeclare(retryQueue, true, false, false, false, amqp.Table{
+		"x-dead-letter-exchange":    mainExchange,
+		"x-dead-letter-routing-key": mainRoutingKey,
+	}); err != nil {
+		return errors.Wrap(err, "failed to declare retry queue")
+	}
+	if err := ch.QueueBind(retryQueue, retryRoutingKey, retryExchange, false, nil); err != nil {
+		return errors.Wrap(err, "failed to bind retry queue")
+	}
+
+	if err := ch.ExchangeDeclare(dlExchange, amqp.ExchangeDirect, true, false, false, false, nil); err != nil {
+		return errors.Wrap(err, "failed to declare dead-letter exchange")
+	}
+	dlQueue := mainQueue + ".dead-letter"
+	if _, err := ch.QueueDeclare(dlQueue, true, false, false, false, nil); err != nil {
+		return errors.Wrap(err, "failed to declare dead-letter queue")
+	}
+	if err := ch.QueueBind(dlQueue, dlRoutingKey, dlExchange, false, nil); err != nil {
+		return errors.Wrap(err, "failed to bind dead-letter queue")
+	}
+
+	return nil
+}
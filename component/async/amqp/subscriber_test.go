@@ -0,0 +1,213 @@
+package amqp
+
+import (
+	"context"
+	"testing"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockPublisher struct {
+	exchange string
+	key      string
+	pub      amqp.Publishing
+	err      error
+}
+
+func (m *mockPublisher) Publish(_ context.Context, exchange, key string, pub amqp.Publishing) error {
+	m.exchange = exchange
+	m.key = key
+	m.pub = pub
+	return m.err
+}
+
+func decodeString(_ context.Context, del *amqp.Delivery) (interface{}, error) {
+	return string(del.Body), nil
+}
+
+func TestNewSubscriber(t *testing.T) {
+	ep := func(_ context.Context, req interface{}) (interface{}, error) { return req, nil }
+
+	type args struct {
+		ep  EndpointFunc
+		dec DecodeRequestFunc
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{"success", args{ep: ep, dec: decodeString}, false},
+		{"missing endpoint", args{ep: nil, dec: decodeString}, true},
+		{"missing decoder", args{ep: ep, dec: nil}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewSubscriber(tt.args.ep, tt.args.dec)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Nil(t, s)
+			} else {
+				assert.NoError(t, err)
+				assert.NotNil(t, s)
+			}
+		})
+	}
+}
+
+func TestWithResponse_Invalid(t *testing.T) {
+	ep := func(_ context.Context, req interface{}) (interface{}, error) { return req, nil }
+	enc := func(_ context.Context, _ *amqp.Publishing, _ interface{}) error { return nil }
+	pub := &mockPublisher{}
+
+	_, err := NewSubscriber(ep, decodeString, WithResponse(nil, pub))
+	assert.EqualError(t, err, "response encoder is required")
+
+	_, err = NewSubscriber(ep, decodeString, WithResponse(enc, nil))
+	assert.EqualError(t, err, "response publisher is required")
+}
+
+func TestSubscriber_ServeDelivery(t *testing.T) {
+	ep := func(_ context.Context, req interface{}) (interface{}, error) { return req, nil }
+
+	t.Run("no response configured", func(t *testing.T) {
+		s, err := NewSubscriber(ep, decodeString)
+		require.NoError(t, err)
+
+		del := &amqp.Delivery{Body: []byte("hello"), ReplyTo: "reply-queue"}
+		assert.NoError(t, s.ServeDelivery(context.Background(), del))
+	})
+
+	t.Run("no reply-to", func(t *testing.T) {
+		pub := &mockPublisher{}
+		enc := func(_ context.Context, p *amqp.Publishing, resp interface{}) error {
+			p.Body = []byte(resp.(string))
+			return nil
+		}
+		s, err := NewSubscriber(ep, decodeString, WithResponse(enc, pub))
+		require.NoError(t, err)
+
+		del := &amqp.Delivery{Body: []byte("hello")}
+		assert.NoError(t, s.ServeDelivery(context.Background(), del))
+		assert.Empty(t, pub.key)
+	})
+
+	t.Run("replies to reply-to", func(t *testing.T) {
+		pub := &mockPublisher{}
+		enc := func(_ context.Context, p *amqp.Publishing, resp interface{}) error {
+			p.Body = []byte(resp.(string))
+			return nil
+		}
+		s, err := NewSubscriber(ep, decodeString, WithResponse(enc, pub))
+		require.NoError(t, err)
+
+		del := &amqp.Delivery{Body: []byte("hello"), ReplyTo: "reply-queue", CorrelationId: "cor-1"}
+		assert.NoError(t, s.ServeDelivery(context.Background(), del))
+		assert.Equal(t, "reply-queue", pub.key)
+		assert.Equal(t, "cor-1", pub.pub.CorrelationId)
+		assert.Equal(t, []byte("hello"), pub.pub.Body)
+	})
+
+	t.Run("decode error", func(t *testing.T) {
+		s, err := NewSubscriber(ep, func(_ context.Context, _ *amqp.Delivery) (interface{}, error) {
+			return nil, assertErr("bad payload")
+		})
+		require.NoError(t, err)
+
+		err = s.ServeDelivery(context.Background(), &amqp.Delivery{})
+		assert.Error(t, err)
+	})
+
+	t.Run("endpoint error", func(t *testing.T) {
+		s, err := NewSubscriber(func(_ context.Context, _ interface{}) (interface{}, error) {
+			return nil, assertErr("processing failed")
+		}, decodeString)
+		require.NoError(t, err)
+
+		err = s.ServeDelivery(context.Background(), &amqp.Delivery{Body: []byte("hello")})
+		assert.Error(t, err)
+	})
+}
+
+type assertErr string
+
+func (e assertErr) Error() string { return string(e) }
+
+func TestWithDeadLetter_Invalid(t *testing.T) {
+	ep := func(_ context.Context, req interface{}) (interface{}, error) { return req, nil }
+	pub := &mockPublisher{}
+
+	_, err := NewSubscriber(ep, decodeString, WithDeadLetter("", "key", 1, pub))
+	assert.EqualError(t, err, "dead-letter exchange is required")
+
+	_, err = NewSubscriber(ep, decodeString, WithDeadLetter("dlx", "", 1, pub))
+	assert.EqualError(t, err, "dead-letter routing key is required")
+
+	_, err = NewSubscriber(ep, decodeString, WithDeadLetter("dlx", "key", -1, pub))
+	assert.EqualError(t, err, "max retries must be greater or equal than 0")
+
+	_, err = NewSubscriber(ep, decodeString, WithDeadLetter("dlx", "key", 1, nil))
+	assert.EqualError(t, err, "dead-letter publisher is required")
+
+	_, err = NewSubscriber(ep, decodeString, WithDeadLetter("dlx", "key", 1, pub), WithRetryDelay(0))
+	assert.EqualError(t, err, "retry delay must be positive")
+}
+
+func TestSubscriber_ServeDelivery_DeadLetter(t *testing.T) {
+	failingEp := func(_ context.Context, _ interface{}) (interface{}, error) {
+		return nil, assertErr("processing failed")
+	}
+
+	t.Run("retries while under max retries", func(t *testing.T) {
+		pub := &mockPublisher{}
+		s, err := NewSubscriber(failingEp, decodeString, WithDeadLetter("dlx", "dlk", 2, pub))
+		require.NoError(t, err)
+
+		del := &amqp.Delivery{
+			Body:       []byte("hello"),
+			Exchange:   "orders",
+			RoutingKey: "created",
+		}
+
+		require.NoError(t, s.ServeDelivery(context.Background(), del))
+		assert.Equal(t, "orders.retry", pub.exchange)
+		assert.Equal(t, "created.retry", pub.key)
+		assert.Equal(t, 1, pub.pub.Headers[retryCountHeader])
+		assert.Equal(t, "5000", pub.pub.Expiration)
+	})
+
+	t.Run("dead-letters once max retries exceeded", func(t *testing.T) {
+		pub := &mockPublisher{}
+		s, err := NewSubscriber(failingEp, decodeString, WithDeadLetter("dlx", "dlk", 1, pub))
+		require.NoError(t, err)
+
+		del := &amqp.Delivery{
+			Body:       []byte("hello"),
+			Exchange:   "orders",
+			RoutingKey: "created",
+			Headers:    amqp.Table{retryCountHeader: 1},
+		}
+
+		require.NoError(t, s.ServeDelivery(context.Background(), del))
+		assert.Equal(t, "dlx", pub.exchange)
+		assert.Equal(t, "dlk", pub.key)
+	})
+
+	t.Run("without WithDeadLetter, failure is returned unchanged", func(t *testing.T) {
+		s, err := NewSubscriber(failingEp, decodeString)
+		require.NoError(t, err)
+
+		err = s.ServeDelivery(context.Background(), &amqp.Delivery{Body: []byte("hello")})
+		assert.Error(t, err)
+	})
+}
+
+func TestRetryCount(t *testing.T) {
+	assert.Equal(t, 0, retryCount(nil))
+	assert.Equal(t, 0, retryCount(amqp.Table{retryCountHeader: "not-a-number"}))
+	assert.Equal(t, 3, retryCount(amqp.Table{retryCountHeader: 3}))
+	assert.Equal(t, 3, retryCount(amqp.Table{retryCountHeader: int32(3)}))
+	assert.Equal(t, 3, retryCount(amqp.Table{retryCountHeader: int64(3)}))
+}
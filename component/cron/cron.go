@@ -0,0 +1,271 @@
+// Package cron implements a Component that runs registered jobs on their
+// own cron schedule, the same way patron's HTTP/AMQP/Kafka components wrap
+// a request- or message-driven handler into the service lifecycle.
+package cron
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/beatlabs/patron/errors"
+	"github.com/beatlabs/patron/log"
+	"github.com/beatlabs/patron/trace"
+	"github.com/opentracing/opentracing-go"
+	"github.com/robfig/cron/v3"
+)
+
+const cronComponent = "cron"
+
+// OverlapPolicy decides what happens when a job's schedule fires again
+// before its previous run has finished.
+type OverlapPolicy int
+
+const (
+	// OverlapSkip drops the new tick, leaving the job's current run to
+	// finish on its own. This is the default.
+	OverlapSkip OverlapPolicy = iota
+	// OverlapQueue coalesces the new tick into a single pending re-run,
+	// fired as soon as the current run finishes, instead of dropping it.
+	// Ticks that arrive while a re-run is already pending are themselves
+	// dropped, so a job can never fall further than one run behind.
+	OverlapQueue
+)
+
+// JobFunc is invoked on every tick of the schedule it was registered with
+// via WithJob.
+type JobFunc func(ctx context.Context) error
+
+// JobOptionFunc defines an option function for WithJob.
+type JobOptionFunc func(*job) error
+
+// WithJobName overrides a job's name, used to tag its tracing span and
+// label its metrics. It defaults to the job's cron spec.
+func WithJobName(name string) JobOptionFunc {
+	return func(j *job) error {
+		if name == "" {
+			return errors.New("job name is required")
+		}
+		j.name = name
+		return nil
+	}
+}
+
+// WithOverlapPolicy overrides a job's OverlapPolicy. It defaults to
+// OverlapSkip.
+func WithOverlapPolicy(p OverlapPolicy) JobOptionFunc {
+	return func(j *job) error {
+		j.policy = p
+		return nil
+	}
+}
+
+// job wraps a JobFunc with the schedule and policy it was registered with,
+// plus the local, single-process overlap guard fire applies around it.
+type job struct {
+	spec   string
+	name   string
+	fn     JobFunc
+	policy OverlapPolicy
+
+	mu      sync.Mutex
+	running bool
+	queued  bool
+}
+
+// tryStart reports whether the caller may run the job now. If it is
+// already running, the tick is either dropped (OverlapSkip) or coalesced
+// into a single pending re-run (OverlapQueue).
+func (j *job) tryStart() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if !j.running {
+		j.running = true
+		return true
+	}
+	if j.policy == OverlapQueue {
+		j.queued = true
+	}
+	return false
+}
+
+// finish reports whether a pending re-run was queued while the caller's
+// run was in flight: if so, the job is left marked as running and the
+// caller should run it again immediately instead of returning.
+func (j *job) finish() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.queued {
+		j.queued = false
+		return true
+	}
+	j.running = false
+	return false
+}
+
+// OptionFunc defines an option function for New.
+type OptionFunc func(*Component) error
+
+// WithJob registers fn to run on every tick of spec, either a 6-field `sec
+// min hour dom mon dow` cron expression or a `@every 5m` / `@daily`-style
+// shortcut. Pass a JobOptionFunc to override the job's default name (spec)
+// or its OverlapPolicy (OverlapSkip).
+func WithJob(spec string, fn JobFunc, oo ...JobOptionFunc) OptionFunc {
+	return func(c *Component) error {
+		if spec == "" {
+			return errors.New("cron spec is required")
+		}
+		if fn == nil {
+			return errors.New("job function is required")
+		}
+
+		j := &job{spec: spec, name: spec, fn: fn}
+		for _, o := range oo {
+			if err := o(j); err != nil {
+				return err
+			}
+		}
+
+		c.jobs = append(c.jobs, j)
+		return nil
+	}
+}
+
+// WithLocker configures a distributed lock that gates every job's
+// execution, on top of (not instead of) each job's own OverlapPolicy, so
+// that replicas running the same Component against the same schedules
+// fire a given tick once, not once per replica. ttl bounds how long a lock
+// is held, in case a replica dies mid-run without releasing it; pick it
+// comfortably longer than a job is ever expected to run.
+func WithLocker(l Locker, ttl time.Duration) OptionFunc {
+	return func(c *Component) error {
+		if l == nil {
+			return errors.New("locker is required")
+		}
+		if ttl <= 0 {
+			return errors.New("lock ttl must be positive")
+		}
+		c.locker = l
+		c.lockTTL = ttl
+		return nil
+	}
+}
+
+// Component runs its registered jobs on their own cron schedule, each
+// firing inside a root tracing span tagged with its name and cron
+// expression, so it shows up in the same trace backend as HTTP/AMQP/Kafka
+// component spans, plus success/failure/duration metrics.
+type Component struct {
+	sched   *cron.Cron
+	jobs    []*job
+	locker  Locker
+	lockTTL time.Duration
+}
+
+// New creates a Component from one or more jobs registered via WithJob.
+func New(oo ...OptionFunc) (*Component, error) {
+	c := &Component{sched: cron.New(cron.WithSeconds())}
+
+	for _, o := range oo {
+		if err := o(c); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(c.jobs) == 0 {
+		return nil, errors.New("at least one job is required")
+	}
+
+	return c, nil
+}
+
+// Run schedules every registered job and blocks until ctx is cancelled.
+func (c *Component) Run(ctx context.Context) error {
+	for _, j := range c.jobs {
+		j := j
+		if _, err := c.sched.AddFunc(j.spec, func() { c.fire(ctx, j) }); err != nil {
+			return fmt.Errorf("failed to schedule cron job %q: %w", j.name, err)
+		}
+	}
+
+	c.sched.Start()
+
+	<-ctx.Done()
+	return nil
+}
+
+// Shutdown stops the scheduler from firing further ticks and waits,
+// bounded by ctx, for any job run already in flight to finish.
+func (c *Component) Shutdown(ctx context.Context) error {
+	stopCtx := c.sched.Stop()
+
+	select {
+	case <-stopCtx.Done():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// fire applies j's overlap policy and, once allowed to run, keeps invoking
+// runOnce for as long as OverlapQueue keeps coalescing further ticks into
+// a pending re-run.
+func (c *Component) fire(ctx context.Context, j *job) {
+	if !j.tryStart() {
+		log.Debugf("cron: %q still running, skipping tick per overlap policy", j.name)
+		countSkipped.WithLabelValues(j.name).Inc()
+		return
+	}
+
+	for {
+		c.runOnce(ctx, j)
+		if !j.finish() {
+			return
+		}
+		log.Debugf("cron: %q running queued re-run coalesced while busy", j.name)
+	}
+}
+
+// runOnce acquires the Component's distributed lock, if configured, then
+// invokes j.fn inside a root tracing span tagged with j's name and spec,
+// recording success/failure/duration metrics around it.
+func (c *Component) runOnce(ctx context.Context, j *job) {
+	if c.locker != nil {
+		key := cronComponent + ":" + j.name
+		ok, err := c.locker.TryLock(ctx, key, c.lockTTL)
+		if err != nil {
+			log.Errorf("cron: failed to acquire lock for %q: %v", j.name, err)
+			return
+		}
+		if !ok {
+			log.Debugf("cron: %q locked by another replica, skipping tick", j.name)
+			return
+		}
+		defer func() {
+			if err := c.locker.Unlock(ctx, key); err != nil {
+				log.Errorf("cron: failed to release lock for %q: %v", j.name, err)
+			}
+		}()
+	}
+
+	sp, sctx := trace.ChildSpan(ctx, trace.ComponentOpName(cronComponent, j.name), cronComponent,
+		opentracing.Tag{Key: "cron.job", Value: j.name},
+		opentracing.Tag{Key: "cron.spec", Value: j.spec},
+	)
+
+	start := time.Now()
+	err := j.fn(sctx)
+	jobDuration.WithLabelValues(j.name).Observe(time.Since(start).Seconds())
+
+	if err != nil {
+		log.Errorf("cron: job %q failed: %v", j.name, err)
+		countFailures.WithLabelValues(j.name).Inc()
+		trace.SpanError(sp)
+		return
+	}
+	countSuccesses.WithLabelValues(j.name).Inc()
+	trace.SpanSuccess(sp)
+}
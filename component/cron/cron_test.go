@@ -0,0 +1,207 @@
+package cron
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func noopJob(_ context.Context) error { return nil }
+
+func TestWithJob_Invalid(t *testing.T) {
+	tests := map[string]struct {
+		spec string
+		fn   JobFunc
+	}{
+		"missing spec": {spec: "", fn: noopJob},
+		"missing func": {spec: "@every 1h", fn: nil},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := New(WithJob(tt.spec, tt.fn))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestWithJobName_Invalid(t *testing.T) {
+	_, err := New(WithJob("@every 1h", noopJob, WithJobName("")))
+	assert.Error(t, err)
+}
+
+func TestWithLocker_Invalid(t *testing.T) {
+	tests := map[string]struct {
+		l   Locker
+		ttl time.Duration
+	}{
+		"missing locker":   {l: nil, ttl: time.Second},
+		"non-positive ttl": {l: &stubLocker{}, ttl: 0},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := New(WithJob("@every 1h", noopJob), WithLocker(tt.l, tt.ttl))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestNew_NoJobs(t *testing.T) {
+	_, err := New()
+	assert.Error(t, err)
+}
+
+func TestNew(t *testing.T) {
+	c, err := New(WithJob("@every 1h", noopJob, WithJobName("my-job"), WithOverlapPolicy(OverlapQueue)))
+	require.NoError(t, err)
+	require.Len(t, c.jobs, 1)
+	assert.Equal(t, "my-job", c.jobs[0].name)
+	assert.Equal(t, OverlapQueue, c.jobs[0].policy)
+}
+
+func TestComponent_fire_OverlapSkip(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	c, err := New(WithJob("@every 1h", func(_ context.Context) error {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return nil
+	}))
+	require.NoError(t, err)
+
+	j := c.jobs[0]
+	done := make(chan struct{})
+	go func() {
+		c.fire(context.Background(), j)
+		close(done)
+	}()
+
+	waitUntilRunning(t, j)
+
+	// Fired while the first run is still in flight: OverlapSkip drops it.
+	c.fire(context.Background(), j)
+	close(release)
+	<-done
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestComponent_fire_OverlapQueue(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	c, err := New(WithJob("@every 1h", func(_ context.Context) error {
+		n := atomic.AddInt32(&calls, 1)
+		if n == 1 {
+			<-release
+		}
+		return nil
+	}, WithOverlapPolicy(OverlapQueue)))
+	require.NoError(t, err)
+
+	j := c.jobs[0]
+	done := make(chan struct{})
+	go func() {
+		c.fire(context.Background(), j)
+		close(done)
+	}()
+
+	waitUntilRunning(t, j)
+
+	// Fired while the first run is still in flight: OverlapQueue coalesces
+	// it into a single pending re-run instead of dropping it.
+	c.fire(context.Background(), j)
+	close(release)
+	<-done
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestComponent_runOnce_FailingLocker(t *testing.T) {
+	var calls int32
+	c, err := New(WithJob("@every 1h", func(_ context.Context) error {
+		atomic.AddInt32(&calls, 1)
+		return nil
+	}), WithLocker(&stubLocker{err: errors.New("boom")}, time.Second))
+	require.NoError(t, err)
+
+	c.runOnce(context.Background(), c.jobs[0])
+
+	assert.Equal(t, int32(0), atomic.LoadInt32(&calls))
+}
+
+func TestComponent_runOnce_LockedElsewhere(t *testing.T) {
+	var calls int32
+	c, err := New(WithJob("@every 1h", func(_ context.Context) error {
+		atomic.AddInt32(&calls, 1)
+		return nil
+	}), WithLocker(&stubLocker{locked: false}, time.Second))
+	require.NoError(t, err)
+
+	c.runOnce(context.Background(), c.jobs[0])
+
+	assert.Equal(t, int32(0), atomic.LoadInt32(&calls))
+}
+
+func TestComponent_RunAndShutdown(t *testing.T) {
+	c, err := New(WithJob("@every 1h", noopJob))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- c.Run(ctx) }()
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after context cancellation")
+	}
+
+	shutdownCtx, scancel := context.WithTimeout(context.Background(), time.Second)
+	defer scancel()
+	assert.NoError(t, c.Shutdown(shutdownCtx))
+}
+
+// waitUntilRunning blocks until j has been marked running by tryStart, so
+// callers can deterministically fire a second, overlapping tick instead of
+// racing the goroutine that runs the first one.
+func waitUntilRunning(t *testing.T, j *job) {
+	t.Helper()
+	deadline := time.After(time.Second)
+	for {
+		j.mu.Lock()
+		running := j.running
+		j.mu.Unlock()
+		if running {
+			return
+		}
+		select {
+		case <-deadline:
+			t.Fatal("job never started running")
+		case <-time.After(time.Millisecond):
+		}
+	}
+}
+
+type stubLocker struct {
+	locked bool
+	err    error
+}
+
+func (l *stubLocker) TryLock(_ context.Context, _ string, _ time.Duration) (bool, error) {
+	if l.err != nil {
+		return false, l.err
+	}
+	return l.locked, nil
+}
+
+func (l *stubLocker) Unlock(_ context.Context, _ string) error {
+	return nil
+}
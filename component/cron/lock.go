@@ -0,0 +1,17 @@
+package cron
+
+import (
+	"context"
+	"time"
+)
+
+// Locker coordinates a job's execution across replicas of the same
+// Component, so that a clustered deployment fires a given tick once,
+// instead of once per replica, on top of (not instead of) each job's own,
+// local OverlapPolicy. TryLock reports whether the lock for key was
+// acquired; implementations should make the lock self-expire after ttl in
+// case the replica holding it dies before calling Unlock.
+type Locker interface {
+	TryLock(ctx context.Context, key string, ttl time.Duration) (bool, error)
+	Unlock(ctx context.Context, key string) error
+}
@@ -0,0 +1,46 @@
+package cron
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	countSuccesses *prometheus.CounterVec
+	countFailures  *prometheus.CounterVec
+	countSkipped   *prometheus.CounterVec
+	jobDuration    *prometheus.HistogramVec
+)
+
+func init() {
+	countSuccesses = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "component",
+			Subsystem: "cron",
+			Name:      "job_success",
+			Help:      "Cron job firings that completed without error, classified by job name",
+		}, []string{"job"},
+	)
+	countFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "component",
+			Subsystem: "cron",
+			Name:      "job_failure",
+			Help:      "Cron job firings that returned an error, classified by job name",
+		}, []string{"job"},
+	)
+	countSkipped = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "component",
+			Subsystem: "cron",
+			Name:      "job_skipped",
+			Help:      "Cron ticks that did not start a run because the job's previous run was still in progress, whether dropped or coalesced into a pending re-run, classified by job name",
+		}, []string{"job"},
+	)
+	jobDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "component",
+			Subsystem: "cron",
+			Name:      "job_duration_seconds",
+			Help:      "Cron job execution duration in seconds, classified by job name",
+		}, []string{"job"},
+	)
+	prometheus.MustRegister(countSuccesses, countFailures, countSkipped, jobDuration)
+}
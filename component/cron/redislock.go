@@ -0,0 +1,82 @@
+package cron
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/beatlabs/patron/errors"
+	"github.com/beatlabs/patron/trace/redis"
+)
+
+// unlockScript releases key only if it is still set to the token the
+// releasing TryLock call acquired it with, so a stale Unlock - e.g. one
+// that arrives after ttl has already let another replica acquire key -
+// can never release a lock it no longer owns.
+const unlockScript = `if redis.call("get", KEYS[1]) == ARGV[1] then return redis.call("del", KEYS[1]) else return 0 end`
+
+// RedisLocker is a Locker backed by Redis, using SET key token NX PX ttl to
+// acquire a key and the token-checked unlockScript to release it.
+type RedisLocker struct {
+	rdb *redis.Client
+
+	mu     sync.Mutex
+	tokens map[string]string
+}
+
+// NewRedisLocker creates a Locker backed by rdb.
+func NewRedisLocker(rdb *redis.Client) (*RedisLocker, error) {
+	if rdb == nil {
+		return nil, errors.New("redis client is required")
+	}
+	return &RedisLocker{rdb: rdb, tokens: make(map[string]string)}, nil
+}
+
+// TryLock acquires key for ttl via SET NX PX, tagging it with a random
+// token so Unlock can later prove it still owns the lock before releasing
+// it.
+func (l *RedisLocker) TryLock(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	token, err := randomToken()
+	if err != nil {
+		return false, errors.Wrap(err, "failed to generate lock token")
+	}
+
+	err = l.rdb.Do(ctx, "set", key, token, "nx", "px", ttl.Milliseconds()).Err()
+	if err == redis.Nil {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	l.mu.Lock()
+	l.tokens[key] = token
+	l.mu.Unlock()
+
+	return true, nil
+}
+
+// Unlock releases key, provided it is still held by the token this
+// RedisLocker's TryLock acquired it with.
+func (l *RedisLocker) Unlock(ctx context.Context, key string) error {
+	l.mu.Lock()
+	token, ok := l.tokens[key]
+	delete(l.tokens, key)
+	l.mu.Unlock()
+
+	if !ok {
+		return errors.New("no lock held for key")
+	}
+
+	return l.rdb.Do(ctx, "eval", unlockScript, 1, key, token).Err()
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
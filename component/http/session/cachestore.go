@@ -0,0 +1,73 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/beatlabs/patron/cache"
+)
+
+// CacheStore adapts any cache.TTLCache into a SessionStore, so a session
+// store comes for free once a Redis- or Memcache-backed TTLCache (see
+// cache/redis and cache/memcache) is already wired for another purpose.
+// Sessions are JSON-encoded before being handed to the cache, since
+// TTLCache implementations are only guaranteed to round-trip strings.
+type CacheStore struct {
+	cache cache.TTLCache
+}
+
+// NewCacheStore creates a CacheStore backed by c.
+func NewCacheStore(c cache.TTLCache) *CacheStore {
+	return &CacheStore{cache: c}
+}
+
+// Get implements SessionStore.
+func (c *CacheStore) Get(_ context.Context, id string) (*Session, bool, error) {
+	v, ok, err := c.cache.Get(id)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get session %q: %w", id, err)
+	}
+	if !ok {
+		return nil, false, nil
+	}
+
+	s, ok := v.(string)
+	if !ok {
+		return nil, false, fmt.Errorf("cached session %q is not a string", id)
+	}
+
+	var d sessionData
+	if err := json.Unmarshal([]byte(s), &d); err != nil {
+		return nil, false, fmt.Errorf("failed to decode session %q: %w", id, err)
+	}
+
+	return fromSessionData(d), true, nil
+}
+
+// Save implements SessionStore. Sessions with no TTL set fall back to
+// DefaultIdleTTL, since TTLCache has no untimed Set.
+func (c *CacheStore) Save(_ context.Context, sess *Session) error {
+	ttl := sess.TTL()
+	if ttl <= 0 {
+		ttl = DefaultIdleTTL
+	}
+
+	b, err := json.Marshal(sess.snapshot())
+	if err != nil {
+		return fmt.Errorf("failed to encode session %q: %w", sess.ID(), err)
+	}
+
+	if err := c.cache.SetTTL(sess.ID(), string(b), ttl); err != nil {
+		return fmt.Errorf("failed to save session %q: %w", sess.ID(), err)
+	}
+	return nil
+}
+
+// Destroy implements SessionStore.
+func (c *CacheStore) Destroy(_ context.Context, id string) error {
+	if err := c.cache.Remove(id); err != nil {
+		return fmt.Errorf("failed to destroy session %q: %w", id, err)
+	}
+	return nil
+}
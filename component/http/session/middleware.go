@@ -0,0 +1,250 @@
+package session
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"time"
+
+	patronhttp "github.com/beatlabs/patron/component/http"
+	"github.com/beatlabs/patron/log"
+)
+
+const (
+	defaultCookieName = "_session"
+	sessionIDBytes    = 32
+)
+
+type ctxKey struct{}
+
+// FromContext returns the Session NewSessionMiddleware attached to ctx, and
+// false if there isn't one (e.g. the handler isn't behind the middleware).
+func FromContext(ctx context.Context) (*Session, bool) {
+	s, ok := ctx.Value(ctxKey{}).(*Session)
+	return s, ok
+}
+
+// SessionOption configures NewSessionMiddleware.
+type SessionOption func(*sessionConfig)
+
+// sessionConfig holds the options gathered by NewSessionMiddleware.
+type sessionConfig struct {
+	secret     []byte
+	cookieName string
+	cookiePath string
+	domain     string
+	secure     bool
+	sameSite   http.SameSite
+	idleTTL    time.Duration
+}
+
+// WithSecret sets the server secret the session-id cookie is HMAC-signed
+// with. Without it, NewSessionMiddleware signs with a secret generated once
+// at startup, so existing cookies won't verify across a process restart.
+func WithSecret(secret []byte) SessionOption {
+	return func(c *sessionConfig) {
+		c.secret = secret
+	}
+}
+
+// WithCookieName overrides the cookie name the session ID is stored under.
+// It defaults to "_session".
+func WithCookieName(name string) SessionOption {
+	return func(c *sessionConfig) {
+		c.cookieName = name
+	}
+}
+
+// WithCookieOptions overrides the path, domain, Secure flag and SameSite
+// mode of the session cookie. It defaults to path "/", no domain, Secure
+// false, and SameSite=Lax.
+func WithCookieOptions(path, domain string, secure bool, sameSite http.SameSite) SessionOption {
+	return func(c *sessionConfig) {
+		c.cookiePath = path
+		c.domain = domain
+		c.secure = secure
+		c.sameSite = sameSite
+	}
+}
+
+// WithIdleTTL sets the absolute-idle timeout new sessions are created with,
+// pushed into the store's SetTTL on every save. It defaults to
+// DefaultIdleTTL. A Session can still override it for itself via
+// Session.SetTTL.
+func WithIdleTTL(ttl time.Duration) SessionOption {
+	return func(c *sessionConfig) {
+		c.idleTTL = ttl
+	}
+}
+
+// NewSessionMiddleware creates a MiddlewareFunc that loads the session
+// identified by a signed session-id cookie from store (creating a new,
+// empty one if the cookie is missing, unverifiable, or unknown to store),
+// attaches it to the request context for FromContext, and on response
+// commit saves it back to store, rotating the cookie if Session.Regenerate
+// was called.
+func NewSessionMiddleware(store SessionStore, opts ...SessionOption) patronhttp.MiddlewareFunc {
+	c := &sessionConfig{
+		cookieName: defaultCookieName,
+		cookiePath: "/",
+		sameSite:   http.SameSiteLaxMode,
+		idleTTL:    DefaultIdleTTL,
+	}
+	for _, o := range opts {
+		o(c)
+	}
+	if len(c.secret) == 0 {
+		c.secret = randomSecret()
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			sess, hadCookie := loadSession(ctx, r, store, c)
+
+			sw := &sessionWriter{ResponseWriter: w, ctx: ctx, sess: sess, store: store, cfg: c, hadCookie: hadCookie}
+			next.ServeHTTP(sw, r.WithContext(context.WithValue(ctx, ctxKey{}, sess)))
+			sw.commit()
+		})
+	}
+}
+
+// loadSession resolves the Session for r: verifies and decodes the signed
+// session-id cookie, if any, and looks it up in store; it falls back to a
+// brand-new session on any miss or failure. hadCookie reports whether r
+// already carried a verifiable cookie, so the middleware can tell a
+// first-visit from a second request for the same session.
+func loadSession(ctx context.Context, r *http.Request, store SessionStore, c *sessionConfig) (sess *Session, hadCookie bool) {
+	cookie, err := r.Cookie(c.cookieName)
+	if err == nil {
+		if id, ok := verifySignedID(c.secret, cookie.Value); ok {
+			if s, found, err := store.Get(ctx, id); err == nil && found {
+				return s, true
+			} else if err != nil {
+				log.Errorf("failed to load session %q: %v", id, err)
+			}
+		}
+	}
+
+	return newSession(newSessionID(), c.idleTTL), false
+}
+
+// sessionWriter wraps the next handler's http.ResponseWriter so that, the
+// first time a response header or body is about to be flushed, it commits
+// the session: setting or rotating the cookie and saving it to the store.
+// This has to happen before the real WriteHeader/Write, since a Set-Cookie
+// header is meaningless once the response has already started.
+type sessionWriter struct {
+	http.ResponseWriter
+	ctx       context.Context
+	sess      *Session
+	store     SessionStore
+	cfg       *sessionConfig
+	hadCookie bool
+	committed bool
+}
+
+func (w *sessionWriter) WriteHeader(code int) {
+	w.commit()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *sessionWriter) Write(b []byte) (int, error) {
+	w.commit()
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *sessionWriter) commit() {
+	if w.committed {
+		return
+	}
+	w.committed = true
+
+	regenerate := w.sess.shouldRegenerate()
+	if regenerate {
+		w.sess.setID(newSessionID())
+	}
+
+	if regenerate || !w.hadCookie {
+		http.SetCookie(w.ResponseWriter, w.cfg.cookie(signID(w.cfg.secret, w.sess.ID())))
+	}
+
+	if !w.sess.isDirty() && w.hadCookie && !regenerate {
+		return
+	}
+
+	if err := w.store.Save(w.ctx, w.sess); err != nil {
+		log.Errorf("failed to save session %q: %v", w.sess.ID(), err)
+	}
+}
+
+// cookie builds the http.Cookie NewSessionMiddleware sets for a
+// freshly-signed session id value.
+func (c *sessionConfig) cookie(value string) *http.Cookie {
+	return &http.Cookie{
+		Name:     c.cookieName,
+		Value:    value,
+		Path:     c.cookiePath,
+		Domain:   c.domain,
+		Secure:   c.secure,
+		HttpOnly: true,
+		SameSite: c.sameSite,
+	}
+}
+
+// newSessionID generates a fresh, random session identifier.
+func newSessionID() string {
+	raw := make([]byte, sessionIDBytes)
+	if _, err := rand.Read(raw); err != nil {
+		panic("session: failed to read random bytes: " + err.Error())
+	}
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// randomSecret generates a process-lifetime HMAC secret for deployments
+// that don't supply one via WithSecret.
+func randomSecret() []byte {
+	secret := make([]byte, sessionIDBytes)
+	if _, err := rand.Read(secret); err != nil {
+		panic("session: failed to read random bytes: " + err.Error())
+	}
+	return secret
+}
+
+// signID HMAC-signs id with secret, returning "id.mac" base64url-encoded.
+func signID(secret []byte, id string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(id))
+	return base64.RawURLEncoding.EncodeToString([]byte(id)) + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// verifySignedID verifies value as produced by signID and, if valid,
+// returns the session id it carries.
+func verifySignedID(secret []byte, value string) (string, bool) {
+	idPart, macPart, ok := strings.Cut(value, ".")
+	if !ok {
+		return "", false
+	}
+
+	idBytes, err := base64.RawURLEncoding.DecodeString(idPart)
+	if err != nil {
+		return "", false
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(macPart)
+	if err != nil {
+		return "", false
+	}
+
+	expected := hmac.New(sha256.New, secret)
+	expected.Write(idBytes)
+	if subtle.ConstantTimeCompare(mac, expected.Sum(nil)) != 1 {
+		return "", false
+	}
+
+	return string(idBytes), true
+}
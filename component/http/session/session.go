@@ -0,0 +1,177 @@
+// Package session provides a server-side HTTP session primitive for the
+// component/http package: NewSessionMiddleware loads a Session from a
+// pluggable SessionStore, attaches it to the request context, and persists
+// it again once the handler has run.
+package session
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultIdleTTL is the absolute-idle timeout applied to a Session when
+// WithIdleTTL isn't used to override it.
+const DefaultIdleTTL = 30 * time.Minute
+
+// Session is the server-side state kept for a single client. Values are
+// read and written through Get/Set/Delete/Flash rather than by exposing the
+// underlying map, so concurrent access from a handler's goroutines is safe
+// and the middleware can tell whether the session needs saving.
+type Session struct {
+	mu         sync.Mutex
+	id         string
+	values     map[string]interface{}
+	flashes    []interface{}
+	ttl        time.Duration
+	regenerate bool
+	dirty      bool
+}
+
+// newSession creates an empty Session identified by id, idle for ttl.
+func newSession(id string, ttl time.Duration) *Session {
+	return &Session{id: id, values: make(map[string]interface{}), ttl: ttl}
+}
+
+// ID returns the session's identifier, as stored in the signed cookie.
+func (s *Session) ID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.id
+}
+
+// TTL returns the absolute-idle timeout NewSessionMiddleware pushes into the
+// store's SetTTL when it saves the session.
+func (s *Session) TTL() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ttl
+}
+
+// SetTTL overrides the idle timeout for this session only.
+func (s *Session) SetTTL(ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ttl = ttl
+	s.dirty = true
+}
+
+// Get returns the value stored under key, if any.
+func (s *Session) Get(key string) (interface{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.values[key]
+	return v, ok
+}
+
+// Set stores value under key.
+func (s *Session) Set(key string, value interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = value
+	s.dirty = true
+}
+
+// Delete removes key from the session, if present.
+func (s *Session) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.values[key]; !ok {
+		return
+	}
+	delete(s.values, key)
+	s.dirty = true
+}
+
+// Flash queues value to be returned exactly once by the next Flashes call,
+// e.g. for a one-time "saved successfully" banner surviving a redirect.
+func (s *Session) Flash(value interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.flashes = append(s.flashes, value)
+	s.dirty = true
+}
+
+// Flashes returns and clears all values queued by Flash.
+func (s *Session) Flashes() []interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.flashes) == 0 {
+		return nil
+	}
+	ff := s.flashes
+	s.flashes = nil
+	s.dirty = true
+	return ff
+}
+
+// Regenerate marks the session to be re-issued under a new ID and cookie on
+// response commit, without losing its values. Call it right after a
+// privilege change (e.g. login) to mitigate session fixation.
+func (s *Session) Regenerate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.regenerate = true
+	s.dirty = true
+}
+
+// shouldRegenerate reports whether Regenerate was called since the session
+// was loaded.
+func (s *Session) shouldRegenerate() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.regenerate
+}
+
+// isDirty reports whether the session was modified since it was created or
+// loaded, i.e. whether the middleware needs to Save it. A brand-new session
+// that was not modified reports false; the middleware saves it anyway, since
+// loadSession reports it as not coming from a stored session.
+func (s *Session) isDirty() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dirty
+}
+
+// setID rebinds the session to a new id, used when regenerating.
+func (s *Session) setID(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.id = id
+	s.regenerate = false
+}
+
+// snapshot takes a point-in-time, lock-free copy of the session's data
+// suitable for serialization by a SessionStore.
+func (s *Session) snapshot() sessionData {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	values := make(map[string]interface{}, len(s.values))
+	for k, v := range s.values {
+		values[k] = v
+	}
+	return sessionData{
+		ID:      s.id,
+		Values:  values,
+		Flashes: append([]interface{}(nil), s.flashes...),
+		TTL:     s.ttl,
+	}
+}
+
+// sessionData is the serializable form of a Session, used by SessionStore
+// implementations that persist sessions out of process (e.g. CacheStore).
+type sessionData struct {
+	ID      string                 `json:"id"`
+	Values  map[string]interface{} `json:"values"`
+	Flashes []interface{}          `json:"flashes"`
+	TTL     time.Duration          `json:"ttl"`
+}
+
+// fromSessionData rebuilds a Session from a previously-taken snapshot.
+func fromSessionData(d sessionData) *Session {
+	return &Session{
+		id:      d.ID,
+		values:  d.Values,
+		flashes: d.Flashes,
+		ttl:     d.TTL,
+	}
+}
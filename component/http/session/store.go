@@ -0,0 +1,76 @@
+package session
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// SessionStore persists Sessions. NewSessionMiddleware calls Get once per
+// request to load the session identified by the incoming cookie, and Save
+// once on response commit to persist whatever the handler changed.
+type SessionStore interface {
+	// Get returns the session identified by id, and false if it doesn't
+	// exist or has expired.
+	Get(ctx context.Context, id string) (*Session, bool, error)
+	// Save persists sess, overwriting any previous value under its ID and
+	// refreshing its idle TTL.
+	Save(ctx context.Context, sess *Session) error
+	// Destroy removes the session identified by id, if any.
+	Destroy(ctx context.Context, id string) error
+}
+
+// MemoryStore is an in-memory SessionStore, suitable for a single-instance
+// deployment or for tests. Expired entries are swept lazily, on the next Get
+// or Save that touches them.
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]*memoryEntry
+}
+
+type memoryEntry struct {
+	session *Session
+	expires time.Time
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]*memoryEntry)}
+}
+
+// Get implements SessionStore.
+func (m *MemoryStore) Get(_ context.Context, id string) (*Session, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.entries[id]
+	if !ok {
+		return nil, false, nil
+	}
+	if !e.expires.IsZero() && time.Now().After(e.expires) {
+		delete(m.entries, id)
+		return nil, false, nil
+	}
+	return e.session, true, nil
+}
+
+// Save implements SessionStore.
+func (m *MemoryStore) Save(_ context.Context, sess *Session) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e := &memoryEntry{session: sess}
+	if ttl := sess.TTL(); ttl > 0 {
+		e.expires = time.Now().Add(ttl)
+	}
+	m.entries[sess.ID()] = e
+	return nil
+}
+
+// Destroy implements SessionStore.
+func (m *MemoryStore) Destroy(_ context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.entries, id)
+	return nil
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/beatlabs/patron/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// FileProvider reads configuration from a YAML or JSON file, flattening nested
+// maps into dotted keys (e.g. {"patron": {"http": {"port": 50000}}} becomes
+// "patron.http.port").
+type FileProvider struct {
+	values map[string]string
+}
+
+// NewFileProvider loads path, detecting the format from its extension
+// (.yaml/.yml or .json).
+func NewFileProvider(path string) (*FileProvider, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read config file")
+	}
+
+	var raw map[string]interface{}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(b, &raw); err != nil {
+			return nil, errors.Wrap(err, "failed to parse yaml config file")
+		}
+	case ".json":
+		if err := json.Unmarshal(b, &raw); err != nil {
+			return nil, errors.Wrap(err, "failed to parse json config file")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported config file extension %q", ext)
+	}
+
+	values := make(map[string]string)
+	flatten("", raw, values)
+	return &FileProvider{values: values}, nil
+}
+
+// Get implements Provider.
+func (p *FileProvider) Get(key string) (string, bool) {
+	v, ok := p.values[key]
+	return v, ok
+}
+
+// GetFloat64 implements Provider.
+func (p *FileProvider) GetFloat64(key string) (float64, bool, error) {
+	return getFloat64(p, key)
+}
+
+// GetInt implements Provider.
+func (p *FileProvider) GetInt(key string) (int, bool, error) {
+	return getInt(p, key)
+}
+
+func flatten(prefix string, raw map[string]interface{}, out map[string]string) {
+	for k, v := range raw {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		switch vv := v.(type) {
+		case map[string]interface{}:
+			flatten(key, vv, out)
+		case map[interface{}]interface{}:
+			nested := make(map[string]interface{}, len(vv))
+			for nk, nval := range vv {
+				nested[fmt.Sprintf("%v", nk)] = nval
+			}
+			flatten(key, nested, out)
+		default:
+			out[key] = fmt.Sprintf("%v", v)
+		}
+	}
+}
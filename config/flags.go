@@ -0,0 +1,104 @@
+package config
+
+import (
+	goerrors "errors"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/beatlabs/patron/errors"
+)
+
+// FlagDef describes a single command-line flag registered with a
+// CommandLineProvider and the Provider key its parsed value is exposed
+// under, e.g. the flag "patron.http.port" feeds the Provider key
+// "PATRON_HTTP_DEFAULT_PORT", mirroring the env var it can replace.
+type FlagDef struct {
+	Name    string
+	Key     string
+	Default string
+	Usage   string
+	Bool    bool
+}
+
+// DefaultFlagDefs returns the FlagDefs for patron's well-known Builder
+// settings, matching the PATRON_* environment variables they can replace.
+func DefaultFlagDefs() []FlagDef {
+	return []FlagDef{
+		{Name: "patron.http.port", Key: "PATRON_HTTP_DEFAULT_PORT", Default: "50000", Usage: "HTTP port for the default component"},
+		{Name: "patron.log.level", Key: "PATRON_LOG_LEVEL", Default: "info", Usage: "log level"},
+		{Name: "patron.jaeger.agent", Key: "PATRON_JAEGER_AGENT_HOST", Default: "0.0.0.0", Usage: "Jaeger agent host"},
+		{Name: "patron.jaeger.sampler.type", Key: "PATRON_JAEGER_SAMPLER_TYPE", Default: "probabilistic", Usage: "Jaeger sampler type"},
+		{Name: "patron.jaeger.sampler.param", Key: "PATRON_JAEGER_SAMPLER_PARAM", Default: "0.0", Usage: "Jaeger sampler param"},
+	}
+}
+
+// CommandLineProvider reads configuration parsed from command-line flags,
+// so a service can be configured under e.g. Kubernetes where env vars are
+// inconvenient. Only flags explicitly passed on the command line are
+// reported by Get; unset flags report not-found, so a CommandLineProvider
+// can be layered ahead of an EnvProvider in a CompositeProvider without
+// its defaults masking the environment.
+type CommandLineProvider struct {
+	values map[string]string
+}
+
+// NewCommandLineProvider parses args against defs, or DefaultFlagDefs if
+// defs is empty. A -h/--help flag prints a dump of all registered keys to
+// w and returns flag.ErrHelp.
+func NewCommandLineProvider(args []string, w io.Writer, defs ...FlagDef) (*CommandLineProvider, error) {
+	if len(defs) == 0 {
+		defs = DefaultFlagDefs()
+	}
+
+	fs := flag.NewFlagSet("patron", flag.ContinueOnError)
+	fs.SetOutput(w)
+	fs.Usage = func() {
+		fmt.Fprintln(w, "patron configuration flags:")
+		for _, d := range defs {
+			fmt.Fprintf(w, "  --%s (key: %s) [default: %s] %s\n", d.Name, d.Key, d.Default, d.Usage)
+		}
+	}
+
+	keys := make(map[string]string, len(defs))
+	for _, d := range defs {
+		keys[d.Name] = d.Key
+		if d.Bool {
+			fs.Bool(d.Name, d.Default == "true", d.Usage)
+		} else {
+			fs.String(d.Name, d.Default, d.Usage)
+		}
+	}
+
+	if err := fs.Parse(args); err != nil {
+		if goerrors.Is(err, flag.ErrHelp) {
+			return nil, err
+		}
+		return nil, errors.Wrap(err, "failed to parse command-line flags")
+	}
+
+	values := make(map[string]string)
+	fs.Visit(func(f *flag.Flag) {
+		if key, ok := keys[f.Name]; ok {
+			values[key] = f.Value.String()
+		}
+	})
+
+	return &CommandLineProvider{values: values}, nil
+}
+
+// Get implements Provider.
+func (p *CommandLineProvider) Get(key string) (string, bool) {
+	v, ok := p.values[key]
+	return v, ok
+}
+
+// GetFloat64 implements Provider.
+func (p *CommandLineProvider) GetFloat64(key string) (float64, bool, error) {
+	return getFloat64(p, key)
+}
+
+// GetInt implements Provider.
+func (p *CommandLineProvider) GetInt(key string) (int, bool, error) {
+	return getInt(p, key)
+}
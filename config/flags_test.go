@@ -0,0 +1,40 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommandLineProvider(t *testing.T) {
+	p, err := NewCommandLineProvider([]string{"--patron.http.port", "8080"}, &bytes.Buffer{})
+	require.NoError(t, err)
+
+	v, ok := p.Get("PATRON_HTTP_DEFAULT_PORT")
+	assert.True(t, ok)
+	assert.Equal(t, "8080", v)
+
+	i, ok, err := p.GetInt("PATRON_HTTP_DEFAULT_PORT")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 8080, i)
+
+	_, ok = p.Get("PATRON_LOG_LEVEL")
+	assert.False(t, ok)
+}
+
+func TestCommandLineProvider_Help(t *testing.T) {
+	var buf bytes.Buffer
+	_, err := NewCommandLineProvider([]string{"--help"}, &buf)
+	assert.True(t, errors.Is(err, flag.ErrHelp))
+	assert.Contains(t, buf.String(), "patron.http.port")
+}
+
+func TestCommandLineProvider_UnknownFlag(t *testing.T) {
+	_, err := NewCommandLineProvider([]string{"--not-a-flag"}, &bytes.Buffer{})
+	assert.Error(t, err)
+}
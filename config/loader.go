@@ -0,0 +1,63 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/beatlabs/patron/log"
+)
+
+// Loader assembles a CompositeProvider from multiple file paths/directories and,
+// finally, the environment, mirroring the way uber-go/fx layers its configuration
+// sources. Paths are layered in the order added, with earlier paths taking
+// precedence; the environment is always consulted last.
+type Loader struct {
+	paths []string
+}
+
+// NewLoader creates an empty Loader.
+func NewLoader() *Loader {
+	return &Loader{}
+}
+
+// AddPath registers a YAML/JSON config file or a directory of them to be loaded.
+func (l *Loader) AddPath(path string) *Loader {
+	l.paths = append(l.paths, path)
+	return l
+}
+
+// Load reads every registered path and returns a Provider overlaying them, in
+// addition order, over the process environment.
+func (l *Loader) Load() (Provider, error) {
+	providers := make([]Provider, 0, len(l.paths)+1)
+
+	for _, p := range l.paths {
+		patterns := []string{p}
+		if fi, err := os.Stat(p); err == nil && fi.IsDir() {
+			patterns = []string{filepath.Join(p, "*.yaml"), filepath.Join(p, "*.yml"), filepath.Join(p, "*.json")}
+		}
+
+		var matches []string
+		for _, pattern := range patterns {
+			mm, err := filepath.Glob(pattern)
+			if err != nil {
+				return nil, err
+			}
+			matches = append(matches, mm...)
+		}
+		if len(matches) == 0 && len(patterns) == 1 {
+			matches = []string{p}
+		}
+		for _, m := range matches {
+			fp, err := NewFileProvider(m)
+			if err != nil {
+				log.Errorf("failed to load config file %s: %v", m, err)
+				continue
+			}
+			providers = append(providers, fp)
+		}
+	}
+
+	providers = append(providers, NewEnvProvider())
+	return NewCompositeProvider(providers...), nil
+}
@@ -0,0 +1,134 @@
+// Package config provides a pluggable source of configuration values for patron
+// services, so that builders and components don't have to hard-code os.LookupEnv
+// calls and tests can swap sources without mutating process-wide env state.
+package config
+
+import (
+	"os"
+	"strconv"
+
+	"github.com/beatlabs/patron/errors"
+)
+
+// Provider is a source of configuration values, keyed by a dotted or
+// underscore-separated name (e.g. "patron.http.port" or "PATRON_HTTP_DEFAULT_PORT",
+// depending on the implementation).
+type Provider interface {
+	// Get returns the raw string value for key, and whether it was found.
+	Get(key string) (string, bool)
+	// GetFloat64 returns key parsed as a float64.
+	GetFloat64(key string) (float64, bool, error)
+	// GetInt returns key parsed as an int.
+	GetInt(key string) (int, bool, error)
+}
+
+// EnvProvider reads configuration from OS environment variables. It is the default
+// provider used by Builder when none is supplied, preserving patron's historical
+// PATRON_* env-var behavior.
+type EnvProvider struct{}
+
+// NewEnvProvider creates a new EnvProvider.
+func NewEnvProvider() *EnvProvider {
+	return &EnvProvider{}
+}
+
+// Get implements Provider.
+func (p *EnvProvider) Get(key string) (string, bool) {
+	return os.LookupEnv(key)
+}
+
+// GetFloat64 implements Provider.
+func (p *EnvProvider) GetFloat64(key string) (float64, bool, error) {
+	return getFloat64(p, key)
+}
+
+// GetInt implements Provider.
+func (p *EnvProvider) GetInt(key string) (int, bool, error) {
+	return getInt(p, key)
+}
+
+// MapProvider reads configuration from an in-memory map, useful for tests and for
+// programmatically assembled configuration.
+type MapProvider struct {
+	values map[string]string
+}
+
+// NewMapProvider creates a new MapProvider backed by values.
+func NewMapProvider(values map[string]string) *MapProvider {
+	if values == nil {
+		values = make(map[string]string)
+	}
+	return &MapProvider{values: values}
+}
+
+// Get implements Provider.
+func (p *MapProvider) Get(key string) (string, bool) {
+	v, ok := p.values[key]
+	return v, ok
+}
+
+// GetFloat64 implements Provider.
+func (p *MapProvider) GetFloat64(key string) (float64, bool, error) {
+	return getFloat64(p, key)
+}
+
+// GetInt implements Provider.
+func (p *MapProvider) GetInt(key string) (int, bool, error) {
+	return getInt(p, key)
+}
+
+// CompositeProvider overlays several providers in priority order: the first
+// provider to report a value for a key wins.
+type CompositeProvider struct {
+	providers []Provider
+}
+
+// NewCompositeProvider creates a CompositeProvider, consulting providers in the
+// order given.
+func NewCompositeProvider(providers ...Provider) *CompositeProvider {
+	return &CompositeProvider{providers: providers}
+}
+
+// Get implements Provider.
+func (p *CompositeProvider) Get(key string) (string, bool) {
+	for _, pr := range p.providers {
+		if v, ok := pr.Get(key); ok {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// GetFloat64 implements Provider.
+func (p *CompositeProvider) GetFloat64(key string) (float64, bool, error) {
+	return getFloat64(p, key)
+}
+
+// GetInt implements Provider.
+func (p *CompositeProvider) GetInt(key string) (int, bool, error) {
+	return getInt(p, key)
+}
+
+func getFloat64(p Provider, key string) (float64, bool, error) {
+	v, ok := p.Get(key)
+	if !ok {
+		return 0, false, nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, true, errors.Wrap(err, "failed to parse float64 value for "+key)
+	}
+	return f, true, nil
+}
+
+func getInt(p Provider, key string) (int, bool, error) {
+	v, ok := p.Get(key)
+	if !ok {
+		return 0, false, nil
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, true, errors.Wrap(err, "failed to parse int value for "+key)
+	}
+	return i, true, nil
+}
@@ -0,0 +1,46 @@
+package config
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMapProvider(t *testing.T) {
+	p := NewMapProvider(map[string]string{"patron.http.port": "50001", "patron.jaeger.sampler.param": "0.5"})
+
+	v, ok := p.Get("patron.http.port")
+	assert.True(t, ok)
+	assert.Equal(t, "50001", v)
+
+	i, ok, err := p.GetInt("patron.http.port")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 50001, i)
+
+	f, ok, err := p.GetFloat64("patron.jaeger.sampler.param")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 0.5, f)
+
+	_, ok = p.Get("missing")
+	assert.False(t, ok)
+}
+
+func TestCompositeProvider(t *testing.T) {
+	require.NoError(t, os.Setenv("PATRON_TEST_COMPOSITE", "from-env"))
+	defer func() { require.NoError(t, os.Unsetenv("PATRON_TEST_COMPOSITE")) }()
+
+	top := NewMapProvider(map[string]string{"PATRON_TEST_COMPOSITE": "from-map"})
+	c := NewCompositeProvider(top, NewEnvProvider())
+
+	v, ok := c.Get("PATRON_TEST_COMPOSITE")
+	assert.True(t, ok)
+	assert.Equal(t, "from-map", v)
+
+	v, ok = c.Get("PATRON_TEST_COMPOSITE_ONLY_IN_ENV")
+	assert.False(t, ok)
+	assert.Empty(t, v)
+}
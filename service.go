@@ -2,12 +2,17 @@ package patron
 
 import (
 	"context"
+	goerrors "errors"
+	"flag"
 	"os"
 	"os/signal"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"syscall"
+	"time"
 
+	"github.com/beatlabs/patron/config"
 	"github.com/beatlabs/patron/errors"
 	"github.com/beatlabs/patron/log"
 	"github.com/beatlabs/patron/log/zerolog"
@@ -47,12 +52,23 @@ type Component interface {
 	Run(ctx context.Context) error
 }
 
+// ShutdownableComponent is an optional extension to Component. A component
+// that implements it has Shutdown called, with a context bounded by
+// Builder.WithShutdownTimeout, once the service begins terminating.
+type ShutdownableComponent interface {
+	Shutdown(ctx context.Context) error
+}
+
 // Service is responsible for managing and setting up everything.
 // The service will start by default a HTTP component in order to host management endpoint.
 type service struct {
-	cps           []Component
-	termSig       chan os.Signal
-	sighupHandler func()
+	cps             []Component
+	termSig         chan os.Signal
+	sighupHandler   func()
+	startupTimeout  time.Duration
+	shutdownTimeout time.Duration
+	preRun          func(context.Context) error
+	postShutdown    func()
 }
 
 func new(components []Component, sighubHandler func()) (*service, error) {
@@ -83,30 +99,163 @@ func (s *service) setupOSSignal() {
 // If a component returns a error the service is responsible for shutting down
 // all components and terminate itself.
 func (s *service) Run() error {
+	if s.preRun != nil {
+		if err := s.runPreRun(); err != nil {
+			return errors.Wrap(err, "pre-run hook failed")
+		}
+	}
+
 	ctx, cnl := context.WithCancel(context.Background())
 	chErr := make(chan error, len(s.cps))
+	running := make([]int32, len(s.cps))
 	wg := sync.WaitGroup{}
 	wg.Add(len(s.cps))
-	for _, cp := range s.cps {
-		go func(c Component) {
+	for i, cp := range s.cps {
+		atomic.StoreInt32(&running[i], 1)
+		go func(i int, c Component) {
 			defer wg.Done()
-			chErr <- c.Run(ctx)
-		}(cp)
+			err := c.Run(ctx)
+			chErr <- err
+			atomic.StoreInt32(&running[i], 0)
+		}(i, cp)
 	}
 
 	var ee []error
 	ee = append(ee, s.waitTermination(chErr))
 	cnl()
 
-	wg.Wait()
-	close(chErr)
+	// A single deadline bounds the whole shutdown sequence: calling Shutdown
+	// on every ShutdownableComponent and waiting for every Run goroutine to
+	// return, so WithShutdownTimeout(d) never costs more than d regardless of
+	// how that time is split between the two phases.
+	var deadline time.Time
+	if s.shutdownTimeout > 0 {
+		deadline = time.Now().Add(s.shutdownTimeout)
+	}
+
+	ee = append(ee, s.shutdownComponents(deadline)...)
 
-	for err := range chErr {
-		ee = append(ee, err)
+	if waitWithDeadline(&wg, deadline) {
+		close(chErr)
+		for err := range chErr {
+			ee = append(ee, err)
+		}
+	} else {
+		for i, cp := range s.cps {
+			if atomic.LoadInt32(&running[i]) == 1 {
+				log.Errorf("component %T did not stop within the shutdown timeout of %s", cp, s.shutdownTimeout)
+			}
+		}
+		// chErr is left open since components still running will still send
+		// to it, but drain whatever errors are already buffered from
+		// components that did stop in time.
+	drain:
+		for {
+			select {
+			case err := <-chErr:
+				ee = append(ee, err)
+			default:
+				break drain
+			}
+		}
+	}
+
+	if s.postShutdown != nil {
+		s.postShutdown()
 	}
+
 	return errors.Aggregate(ee...)
 }
 
+// runPreRun calls s.preRun, racing it against s.startupTimeout if one is set,
+// since a hook that ignores ctx cancellation would otherwise block Run
+// indefinitely despite WithStartupTimeout being configured.
+func (s *service) runPreRun() error {
+	if s.startupTimeout <= 0 {
+		return s.preRun(context.Background())
+	}
+
+	ctx, cnl := context.WithTimeout(context.Background(), s.startupTimeout)
+	defer cnl()
+
+	done := make(chan error, 1)
+	go func() { done <- s.preRun(ctx) }()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return errors.New("pre-run hook exceeded startup timeout")
+	}
+}
+
+// shutdownComponents calls Shutdown on every component that implements
+// ShutdownableComponent, bounded by deadline, and returns the errors
+// collected from them. A component that ignores ctx cancellation no longer
+// blocks the deadline itself, since completion is observed via a WaitGroup
+// raced against deadline rather than by trusting Shutdown to return promptly.
+func (s *service) shutdownComponents(deadline time.Time) []error {
+	ctx := context.Background()
+	if !deadline.IsZero() {
+		var cnl context.CancelFunc
+		ctx, cnl = context.WithDeadline(ctx, deadline)
+		defer cnl()
+	}
+
+	var mu sync.Mutex
+	var ee []error
+	var wg sync.WaitGroup
+
+	for _, cp := range s.cps {
+		sc, ok := cp.(ShutdownableComponent)
+		if !ok {
+			continue
+		}
+		wg.Add(1)
+		go func(sc ShutdownableComponent) {
+			defer wg.Done()
+			if err := sc.Shutdown(ctx); err != nil {
+				log.Errorf("component %T failed to shut down: %v", sc, err)
+				mu.Lock()
+				ee = append(ee, err)
+				mu.Unlock()
+			}
+		}(sc)
+	}
+
+	if !waitWithDeadline(&wg, deadline) {
+		log.Errorf("timed out waiting for components to shut down after %s", time.Until(deadline))
+	}
+
+	// Read ee under mu: a Shutdown call that missed the deadline above is
+	// still running and may still append to ee concurrently with this return.
+	mu.Lock()
+	defer mu.Unlock()
+	return ee
+}
+
+// waitWithDeadline waits for wg to finish, bounded by deadline if one is set,
+// and reports whether it finished in time.
+func waitWithDeadline(wg *sync.WaitGroup, deadline time.Time) bool {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	if deadline.IsZero() {
+		<-done
+		return true
+	}
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(time.Until(deadline)):
+		return false
+	}
+}
+
 func (s *service) waitTermination(chErr <-chan error) error {
 	for {
 		select {
@@ -127,19 +276,43 @@ func (s *service) waitTermination(chErr <-chan error) error {
 
 // Builder definition.
 type Builder struct {
-	errors        []error
-	name          string
-	version       string
-	routes        []http.Route
-	middlewares   []http.MiddlewareFunc
-	healthCheck   http.HealthCheckFunc
-	components    []Component
-	sighupHandler func()
+	errors          []error
+	name            string
+	version         string
+	cfg             config.Provider
+	routes          []http.Route
+	middlewares     []http.MiddlewareFunc
+	healthCheck     http.HealthCheckFunc
+	components      []Component
+	sighupHandler   func()
+	startupTimeout  time.Duration
+	shutdownTimeout time.Duration
+	preRun          func(context.Context) error
+	postShutdown    func()
+	tracerProvider  trace.Provider
+}
+
+// Option configures a Builder at construction time via New, mirroring the
+// optionFunc pattern already used internally by service's sighub.
+type Option func(*Builder) error
+
+// WithTracer overrides the default env-driven Jaeger tracing with p, e.g. a
+// trace/otel.Provider shipping spans to an OTel collector over OTLP. If no
+// WithTracer option is given, New falls back to the PATRON_JAEGER_* config
+// lookups as before.
+func WithTracer(p trace.Provider) Option {
+	return func(b *Builder) error {
+		if p == nil {
+			return errors.New("tracer provider is nil")
+		}
+		b.tracerProvider = p
+		return nil
+	}
 }
 
 // New builder constructor.
-func New(name string, version string) *Builder {
-	b := &Builder{}
+func New(name string, version string, oo ...Option) *Builder {
+	b := &Builder{cfg: config.NewEnvProvider()}
 	var ers []error
 	if name == "" {
 		b.errors = append(ers, errors.New("name is required"))
@@ -153,12 +326,22 @@ func New(name string, version string) *Builder {
 	b.version = version
 	b.healthCheck = http.DefaultHealthCheck
 
+	for _, opt := range oo {
+		if err := opt(b); err != nil {
+			ers = append(ers, err)
+		}
+	}
+
 	err := Setup(name, version)
 	if err != nil {
 		ers = append(ers, err)
 	}
 
-	err = setupDefaultTracing(name, version)
+	if b.tracerProvider != nil {
+		err = b.tracerProvider.Setup(name, version)
+	} else {
+		err = setupDefaultTracing(name, version, b.cfg)
+	}
 	if err != nil {
 		ers = append(ers, err)
 	}
@@ -167,6 +350,42 @@ func New(name string, version string) *Builder {
 	return b
 }
 
+// WithConfigProvider sets the config.Provider the Builder resolves its PATRON_*
+// settings through, e.g. for tests or for configuration sourced from files
+// instead of the process environment. Only lookups made after New returns go
+// through it, i.e. PATRON_HTTP_DEFAULT_PORT when Run creates the default HTTP
+// component; the PATRON_JAEGER_* values are resolved from the environment
+// inside New, and Setup reads PATRON_LOG_LEVEL directly via os.LookupEnv.
+func (b *Builder) WithConfigProvider(cfg config.Provider) *Builder {
+	if cfg == nil {
+		b.errors = append(b.errors, errors.New("config provider is nil"))
+		return b
+	}
+	b.cfg = cfg
+	return b
+}
+
+// WithCommandLineArgs parses args (typically os.Args[1:]) with
+// config.NewCommandLineProvider and layers it ahead of the Builder's current
+// config.Provider, so explicitly-passed flags take precedence while every
+// other PATRON_* lookup falls back unchanged, e.g. to the environment. A
+// -h/--help flag is not treated as an error; it prints the registered keys
+// to os.Stderr and leaves the Builder's config untouched. As with
+// WithConfigProvider, only lookups made after New returns see the flags, so
+// log level and Jaeger flags have no effect on the Builder.
+func (b *Builder) WithCommandLineArgs(args []string, defs ...config.FlagDef) *Builder {
+	cli, err := config.NewCommandLineProvider(args, os.Stderr, defs...)
+	if err != nil {
+		if goerrors.Is(err, flag.ErrHelp) {
+			return b
+		}
+		b.errors = append(b.errors, err)
+		return b
+	}
+	b.cfg = config.NewCompositeProvider(cli, b.cfg)
+	return b
+}
+
 // WithRoutes adds routes to the service.
 func (b *Builder) WithRoutes(rr ...http.Route) *Builder {
 	if len(rr) == 0 {
@@ -212,6 +431,55 @@ func (b *Builder) WithSIGHUP(handler func()) *Builder {
 	return b
 }
 
+// WithStartupTimeout bounds how long WithPreRun's hook may run before Run
+// aborts startup and returns an error.
+func (b *Builder) WithStartupTimeout(d time.Duration) *Builder {
+	if d <= 0 {
+		b.errors = append(b.errors, errors.New("startup timeout must be positive"))
+		return b
+	}
+	b.startupTimeout = d
+	return b
+}
+
+// WithShutdownTimeout bounds how long Run waits for components to shut down,
+// via ShutdownableComponent.Shutdown and via their Run goroutines returning,
+// before forcing a return and logging which components failed to stop in
+// time.
+func (b *Builder) WithShutdownTimeout(d time.Duration) *Builder {
+	if d <= 0 {
+		b.errors = append(b.errors, errors.New("shutdown timeout must be positive"))
+		return b
+	}
+	b.shutdownTimeout = d
+	return b
+}
+
+// WithPreRun registers a hook run once before any component starts, bounded
+// by WithStartupTimeout if set. An error aborts Run before any component is
+// started. Useful for migrations or warmups that would otherwise need to be
+// wrapped as a Component of their own.
+func (b *Builder) WithPreRun(fn func(context.Context) error) *Builder {
+	if fn == nil {
+		b.errors = append(b.errors, errors.New("pre-run hook is nil"))
+		return b
+	}
+	b.preRun = fn
+	return b
+}
+
+// WithPostShutdown registers a hook run once Run has finished shutting down
+// every component, regardless of whether shutdown succeeded or timed out.
+// Useful for flushing buffers or closing resources shared across components.
+func (b *Builder) WithPostShutdown(fn func()) *Builder {
+	if fn == nil {
+		b.errors = append(b.errors, errors.New("post-shutdown hook is nil"))
+		return b
+	}
+	b.postShutdown = fn
+	return b
+}
+
 // Run the service.
 func (b *Builder) Run() error {
 	if len(b.errors) > 0 {
@@ -219,7 +487,7 @@ func (b *Builder) Run() error {
 	}
 
 	defer func() {
-		err := trace.Close()
+		err := b.closeTracing()
 		if err != nil {
 			log.Errorf("failed to close trace %v", err)
 		}
@@ -235,30 +503,43 @@ func (b *Builder) Run() error {
 	if err != nil {
 		return err
 	}
+	s.startupTimeout = b.startupTimeout
+	s.shutdownTimeout = b.shutdownTimeout
+	s.preRun = b.preRun
+	s.postShutdown = b.postShutdown
 	return s.Run()
 	//TODO: fix cli to support the above
 }
 
-func setupDefaultTracing(name, version string) error {
+// closeTracing shuts down whichever tracer New installed: b.tracerProvider
+// if WithTracer was given, or the default env-driven Jaeger tracer otherwise.
+func (b *Builder) closeTracing() error {
+	if b.tracerProvider != nil {
+		return b.tracerProvider.Close()
+	}
+	return trace.Close()
+}
+
+func setupDefaultTracing(name, version string, cfg config.Provider) error {
 	var err error
 
-	host, ok := os.LookupEnv("PATRON_JAEGER_AGENT_HOST")
+	host, ok := cfg.Get("PATRON_JAEGER_AGENT_HOST")
 	if !ok {
 		host = "0.0.0.0"
 	}
-	port, ok := os.LookupEnv("PATRON_JAEGER_AGENT_PORT")
+	port, ok := cfg.Get("PATRON_JAEGER_AGENT_PORT")
 	if !ok {
 		port = "6831"
 	}
 	agent := host + ":" + port
-	tp, ok := os.LookupEnv("PATRON_JAEGER_SAMPLER_TYPE")
+	tp, ok := cfg.Get("PATRON_JAEGER_SAMPLER_TYPE")
 	if !ok {
 		tp = jaeger.SamplerTypeProbabilistic
 	}
 	var prmVal = 0.0
 	var prm = "0.0"
 
-	if prm, ok := os.LookupEnv("PATRON_JAEGER_SAMPLER_PARAM"); ok {
+	if prm, ok := cfg.Get("PATRON_JAEGER_SAMPLER_PARAM"); ok {
 		prmVal, err = strconv.ParseFloat(prm, 64)
 		if err != nil {
 			return errors.Wrap(err, "env var for jaeger sampler param is not valid")
@@ -272,7 +553,7 @@ func setupDefaultTracing(name, version string) error {
 func (b *Builder) createHTTPComponent() (Component, error) {
 	var err error
 	var portVal = int64(50000)
-	port, ok := os.LookupEnv("PATRON_HTTP_DEFAULT_PORT")
+	port, ok := b.cfg.Get("PATRON_HTTP_DEFAULT_PORT")
 	if ok {
 		portVal, err = strconv.ParseInt(port, 10, 64)
 		if err != nil {
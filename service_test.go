@@ -5,7 +5,9 @@ import (
 	"net/http"
 	"os"
 	"testing"
+	"time"
 
+	"github.com/beatlabs/patron/config"
 	"github.com/beatlabs/patron/errors"
 	patronhttp "github.com/beatlabs/patron/sync/http"
 	"github.com/stretchr/testify/assert"
@@ -116,6 +118,32 @@ func TestRun_HttpError(t *testing.T) {
 	require.NoError(t, os.Setenv("PATRON_HTTP_DEFAULT_PORT", "50000"))
 }
 
+func TestWithConfigProvider_Nil(t *testing.T) {
+	err := New("name", "").WithConfigProvider(nil).Run()
+	assert.EqualError(t, err, "config provider is nil\n")
+}
+
+func TestWithConfigProvider_HTTPPort(t *testing.T) {
+	cfg := config.NewMapProvider(map[string]string{"PATRON_HTTP_DEFAULT_PORT": "XXX"})
+	err := New("name", "").WithConfigProvider(cfg).Run()
+	assert.EqualError(t, err, "env var for HTTP default port is not valid: strconv.ParseInt: parsing \"XXX\": invalid syntax")
+}
+
+func TestWithCommandLineArgs_Port(t *testing.T) {
+	err := New("name", "").WithCommandLineArgs([]string{"--patron.http.port", "XXX"}).Run()
+	assert.EqualError(t, err, "env var for HTTP default port is not valid: strconv.ParseInt: parsing \"XXX\": invalid syntax")
+}
+
+func TestWithCommandLineArgs_Help(t *testing.T) {
+	b := New("name", "").WithCommandLineArgs([]string{"--help"})
+	assert.Empty(t, b.errors)
+}
+
+func TestWithCommandLineArgs_UnknownFlag(t *testing.T) {
+	err := New("name", "").WithCommandLineArgs([]string{"--not-a-flag"}).Run()
+	assert.Error(t, err)
+}
+
 func TestRun_Error(t *testing.T) {
 	h := func(_ http.ResponseWriter, _ *http.Request) {
 	}
@@ -127,3 +155,129 @@ func TestRun_Error(t *testing.T) {
 		Run()
 	assert.EqualError(t, err, "failed to run component\n")
 }
+
+func TestWithStartupTimeout_Invalid(t *testing.T) {
+	err := New("name", "").WithStartupTimeout(0).Run()
+	assert.EqualError(t, err, "startup timeout must be positive\n")
+}
+
+func TestWithShutdownTimeout_Invalid(t *testing.T) {
+	err := New("name", "").WithShutdownTimeout(0).Run()
+	assert.EqualError(t, err, "shutdown timeout must be positive\n")
+}
+
+func TestWithPreRun_Nil(t *testing.T) {
+	err := New("name", "").WithPreRun(nil).Run()
+	assert.EqualError(t, err, "pre-run hook is nil\n")
+}
+
+func TestWithPostShutdown_Nil(t *testing.T) {
+	err := New("name", "").WithPostShutdown(nil).Run()
+	assert.EqualError(t, err, "post-shutdown hook is nil\n")
+}
+
+func TestWithTracer_Nil(t *testing.T) {
+	err := WithTracer(nil)(New("name", ""))
+	assert.EqualError(t, err, "tracer provider is nil")
+}
+
+func TestNew_WithTracerOptionError(t *testing.T) {
+	err := New("name", "", WithTracer(nil)).Run()
+	assert.EqualError(t, err, "tracer provider is nil\n")
+}
+
+type shutdownableTestComponent struct {
+	testComponent
+	shutdownCalled bool
+	shutdownErr    error
+}
+
+func (c *shutdownableTestComponent) Shutdown(_ context.Context) error {
+	c.shutdownCalled = true
+	return c.shutdownErr
+}
+
+func TestService_Run_ShutsDownComponents(t *testing.T) {
+	cp := &shutdownableTestComponent{}
+	s, err := new([]Component{cp}, nil)
+	require.NoError(t, err)
+
+	assert.NoError(t, s.Run())
+	assert.True(t, cp.shutdownCalled)
+}
+
+func TestService_Run_CollectsShutdownErrors(t *testing.T) {
+	cp := &shutdownableTestComponent{shutdownErr: errors.New("failed to shut down")}
+	s, err := new([]Component{cp}, nil)
+	require.NoError(t, err)
+
+	err = s.Run()
+	assert.True(t, cp.shutdownCalled)
+	assert.EqualError(t, err, "failed to shut down\n")
+}
+
+func TestService_Run_PreRunError_SkipsComponents(t *testing.T) {
+	cp := &testComponent{}
+	s, err := new([]Component{cp}, nil)
+	require.NoError(t, err)
+	s.preRun = func(_ context.Context) error { return errors.New("migration failed") }
+
+	err = s.Run()
+	assert.EqualError(t, err, "pre-run hook failed: migration failed")
+}
+
+func TestService_Run_PreRunRespectsStartupTimeout(t *testing.T) {
+	cp := &testComponent{}
+	s, err := new([]Component{cp}, nil)
+	require.NoError(t, err)
+	s.startupTimeout = 5 * time.Millisecond
+	s.preRun = func(_ context.Context) error {
+		time.Sleep(50 * time.Millisecond)
+		return nil
+	}
+
+	err = s.Run()
+	assert.EqualError(t, err, "pre-run hook failed: pre-run hook exceeded startup timeout")
+}
+
+type hangingTestComponent struct{}
+
+func (hangingTestComponent) Run(ctx context.Context) error {
+	<-ctx.Done()
+	time.Sleep(50 * time.Millisecond)
+	return nil
+}
+
+// quickFailOnCancelComponent returns an error as soon as ctx is cancelled,
+// well within any shutdown deadline used in tests below.
+type quickFailOnCancelComponent struct{}
+
+func (quickFailOnCancelComponent) Run(ctx context.Context) error {
+	<-ctx.Done()
+	return errors.New("quick component failed on shutdown")
+}
+
+func TestService_Run_ShutdownTimeout_KeepsErrorsAlreadyReceived(t *testing.T) {
+	cps := []Component{&testComponent{errorRunning: true}, quickFailOnCancelComponent{}, hangingTestComponent{}}
+	s, err := new(cps, nil)
+	require.NoError(t, err)
+	s.shutdownTimeout = 20 * time.Millisecond
+
+	err = s.Run()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to run component")
+	assert.Contains(t, err.Error(), "quick component failed on shutdown")
+}
+
+func TestService_Run_PostShutdownAlwaysRuns(t *testing.T) {
+	cp := &testComponent{errorRunning: true}
+	s, err := new([]Component{cp}, nil)
+	require.NoError(t, err)
+
+	var postShutdownCalled bool
+	s.postShutdown = func() { postShutdownCalled = true }
+
+	err = s.Run()
+	assert.Error(t, err)
+	assert.True(t, postShutdownCalled)
+}
@@ -0,0 +1,125 @@
+// Package apierror provides a single typed error for HTTP handlers to
+// return, so that every error path produces a consistent, machine-readable
+// response body instead of an ad-hoc string.
+package apierror
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/beatlabs/patron/correlation"
+)
+
+// mediaTypeProblemJSON is the RFC 7807 "Problem Details for HTTP APIs"
+// content type.
+const mediaTypeProblemJSON = "application/problem+json"
+
+// CodeInternal is the stable Code used for APIErrors that wrap an unknown
+// error or a recovered panic.
+const CodeInternal = "internal_error"
+
+// APIError is a typed, structured error returned by HTTP handlers.
+type APIError struct {
+	HTTPCode  int                    `json:"-"`
+	Code      string                 `json:"code"`
+	Message   string                 `json:"message"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+	RequestID string                 `json:"request_id,omitempty"`
+}
+
+// New creates an APIError with the given HTTP status code, stable code and
+// human-readable message.
+func New(httpCode int, code, message string) *APIError {
+	return &APIError{HTTPCode: httpCode, Code: code, Message: message}
+}
+
+// Internal creates the 500 APIError used for unknown errors and recovered
+// panics.
+func Internal() *APIError {
+	return New(http.StatusInternalServerError, CodeInternal, "an internal error occurred")
+}
+
+// Error implements error.
+func (e *APIError) Error() string {
+	return e.Message
+}
+
+// WithDetail attaches a key-value pair of additional, machine-readable
+// context to the error, e.g. the field that failed validation.
+func (e *APIError) WithDetail(key string, value interface{}) *APIError {
+	if e.Details == nil {
+		e.Details = make(map[string]interface{})
+	}
+	e.Details[key] = value
+	return e
+}
+
+// WithRequestID sets the request id reported alongside the error. WriteError
+// sets this automatically from the request's correlation ID when it is
+// empty, so callers typically don't need to call this themselves.
+func (e *APIError) WithRequestID(id string) *APIError {
+	e.RequestID = id
+	return e
+}
+
+// problemDocument is the RFC 7807 JSON representation of an APIError.
+type problemDocument struct {
+	Title     string                 `json:"title"`
+	Status    int                    `json:"status"`
+	Code      string                 `json:"code"`
+	RequestID string                 `json:"request_id,omitempty"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+}
+
+// WriteError writes err to w as a JSON body, content-negotiated between
+// plain JSON and RFC 7807 problem+json based on the request's Accept
+// header. Any error that is not an *APIError is treated as an opaque
+// Internal error, so handlers never leak unstructured error strings. The
+// request's correlation ID, if set, is attached to the response body.
+//
+// err is never mutated: handlers are expected to reuse a single
+// package-level *APIError (e.g. var ErrNotFound = apierror.New(...)) across
+// requests, and writing the request ID directly into it would race
+// concurrent requests sharing the same value.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	apiErr, ok := err.(*APIError)
+	if !ok {
+		apiErr = Internal()
+	}
+	if apiErr.RequestID == "" {
+		cp := *apiErr
+		cp.RequestID = r.Header.Get(correlation.HeaderID)
+		apiErr = &cp
+	}
+
+	if wantsProblemJSON(r) {
+		writeProblemJSON(w, apiErr)
+		return
+	}
+	writeJSON(w, apiErr)
+}
+
+func wantsProblemJSON(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), mediaTypeProblemJSON)
+}
+
+func writeJSON(w http.ResponseWriter, e *APIError) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(e.HTTPCode)
+	_ = json.NewEncoder(w).Encode(e)
+}
+
+func writeProblemJSON(w http.ResponseWriter, e *APIError) {
+	w.Header().Set("Content-Type", mediaTypeProblemJSON+"; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(e.HTTPCode)
+	_ = json.NewEncoder(w).Encode(problemDocument{
+		Title:     e.Message,
+		Status:    e.HTTPCode,
+		Code:      e.Code,
+		RequestID: e.RequestID,
+		Details:   e.Details,
+	})
+}
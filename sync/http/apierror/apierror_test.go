@@ -0,0 +1,84 @@
+package apierror
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/beatlabs/patron/correlation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteError_JSON(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(correlation.HeaderID, "cor-id")
+	w := httptest.NewRecorder()
+
+	WriteError(w, r, New(http.StatusBadRequest, "bad_input", "invalid input").WithDetail("field", "name"))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+
+	var got APIError
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+	assert.Equal(t, "bad_input", got.Code)
+	assert.Equal(t, "invalid input", got.Message)
+	assert.Equal(t, "cor-id", got.RequestID)
+	assert.Equal(t, "name", got.Details["field"])
+}
+
+func TestWriteError_ProblemJSON(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept", mediaTypeProblemJSON)
+	w := httptest.NewRecorder()
+
+	WriteError(w, r, New(http.StatusNotFound, "not_found", "resource not found"))
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, mediaTypeProblemJSON+"; charset=utf-8", w.Header().Get("Content-Type"))
+
+	var got problemDocument
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+	assert.Equal(t, "not_found", got.Code)
+	assert.Equal(t, "resource not found", got.Title)
+	assert.Equal(t, http.StatusNotFound, got.Status)
+}
+
+func TestWriteError_UnknownError(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+
+	WriteError(w, r, assertError("boom"))
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	var got APIError
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+	assert.Equal(t, CodeInternal, got.Code)
+}
+
+type assertError string
+
+func (e assertError) Error() string { return string(e) }
+
+func TestWriteError_DoesNotMutateSharedAPIError(t *testing.T) {
+	errNotFound := New(http.StatusNotFound, "not_found", "resource not found")
+
+	r1 := httptest.NewRequest(http.MethodGet, "/", nil)
+	r1.Header.Set(correlation.HeaderID, "cor-id-1")
+	WriteError(httptest.NewRecorder(), r1, errNotFound)
+
+	r2 := httptest.NewRequest(http.MethodGet, "/", nil)
+	r2.Header.Set(correlation.HeaderID, "cor-id-2")
+	w2 := httptest.NewRecorder()
+	WriteError(w2, r2, errNotFound)
+
+	assert.Empty(t, errNotFound.RequestID, "WriteError must not mutate the shared *APIError it was given")
+
+	var got APIError
+	require.NoError(t, json.Unmarshal(w2.Body.Bytes(), &got))
+	assert.Equal(t, "cor-id-2", got.RequestID)
+}
@@ -0,0 +1,142 @@
+package http
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/beatlabs/patron/cache"
+	"github.com/beatlabs/patron/encoding/json"
+	"github.com/beatlabs/patron/log"
+)
+
+// cachedResponse is the JSON-serialized form stored in the cache, so that
+// the caching middleware works unmodified against every cache.TTLCache
+// backend, including ones that only store strings (e.g. cache/memcache).
+type cachedResponse struct {
+	Status int                 `json:"status"`
+	Header map[string][]string `json:"header"`
+	Body   []byte              `json:"body"`
+}
+
+// NewCachingMiddleware creates a MiddlewareFunc that caches successful (2xx)
+// GET response bodies in c for ttl, keyed by the request path, query string
+// and the values of varyHeaders. Requests with any other method, or
+// responses with any other status code, bypass the cache untouched.
+func NewCachingMiddleware(c cache.TTLCache, ttl time.Duration, varyHeaders ...string) MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key := cacheKey(r, varyHeaders)
+
+			if cached, ok := getCachedResponse(c, key); ok {
+				writeCachedResponse(w, cached)
+				return
+			}
+
+			cw := newCachingResponseWriter(w)
+			next.ServeHTTP(cw, r)
+
+			if cw.status < http.StatusOK || cw.status >= http.StatusMultipleChoices {
+				return
+			}
+
+			resp := cachedResponse{Status: cw.status, Header: map[string][]string(cw.Header()), Body: cw.body.Bytes()}
+			b, err := json.Encode(resp)
+			if err != nil {
+				log.Errorf("failed to encode response for caching: %v", err)
+				return
+			}
+			if err := c.SetTTL(key, string(b), ttl); err != nil {
+				log.Errorf("failed to write response to cache: %v", err)
+			}
+		})
+	}
+}
+
+func getCachedResponse(c cache.TTLCache, key string) (cachedResponse, bool) {
+	v, ok, err := c.Get(key)
+	if err != nil {
+		log.Errorf("failed to read response from cache: %v", err)
+		return cachedResponse{}, false
+	}
+	if !ok {
+		return cachedResponse{}, false
+	}
+
+	s, ok := v.(string)
+	if !ok {
+		log.Errorf("cached value for key %q is not a string", key)
+		return cachedResponse{}, false
+	}
+
+	var resp cachedResponse
+	if err := json.DecodeRaw([]byte(s), &resp); err != nil {
+		log.Errorf("failed to decode cached response: %v", err)
+		return cachedResponse{}, false
+	}
+	return resp, true
+}
+
+func writeCachedResponse(w http.ResponseWriter, resp cachedResponse) {
+	h := w.Header()
+	for k, v := range resp.Header {
+		h[k] = v
+	}
+	w.WriteHeader(resp.Status)
+	_, _ = w.Write(resp.Body)
+}
+
+func cacheKey(r *http.Request, varyHeaders []string) string {
+	var b strings.Builder
+	b.WriteString(r.URL.Path)
+	b.WriteByte('?')
+	b.WriteString(r.URL.RawQuery)
+	for _, h := range varyHeaders {
+		b.WriteByte('|')
+		b.WriteString(h)
+		b.WriteByte('=')
+		b.WriteString(r.Header.Get(h))
+	}
+	return b.String()
+}
+
+// cachingResponseWriter buffers the response body alongside the status it
+// captures and the wrapped ResponseWriter's headers, so the full response can
+// be replayed from cache on a later hit.
+type cachingResponseWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func newCachingResponseWriter(w http.ResponseWriter) *cachingResponseWriter {
+	return &cachingResponseWriter{ResponseWriter: w, status: http.StatusOK}
+}
+
+// WriteHeader writes the underlying header and saves the status for later caching.
+func (w *cachingResponseWriter) WriteHeader(code int) {
+	w.status = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Write writes to the underlying ResponseWriter while also buffering the
+// body so it can be cached once the handler returns.
+func (w *cachingResponseWriter) Write(d []byte) (int, error) {
+	w.body.Write(d)
+	return w.ResponseWriter.Write(d)
+}
+
+// Flush forwards to the underlying ResponseWriter's http.Flusher, if it
+// implements one, so handlers that stream GET responses keep working behind
+// this middleware instead of silently buffering until they return.
+func (w *cachingResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -2,7 +2,9 @@ package http
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
+	"net"
 	"net/http"
 	"sync"
 	"time"
@@ -10,15 +12,22 @@ import (
 	"github.com/beatlabs/patron/errors"
 	"github.com/beatlabs/patron/log"
 	"github.com/julienschmidt/httprouter"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 )
 
 const (
-	httpPort         = 50000
-	httpReadTimeout  = 5 * time.Second
-	httpWriteTimeout = 10 * time.Second
-	httpIdleTimeout  = 120 * time.Second
+	httpPort                = 50000
+	httpReadTimeout         = 5 * time.Second
+	httpWriteTimeout        = 10 * time.Second
+	httpIdleTimeout         = 120 * time.Second
+	httpShutdownGracePeriod = 5 * time.Second
 )
 
+// defaultTLSNextProtos is the ALPN protocol list a TLS-configured Component
+// advertises unless WithTLSNextProtos overrides it.
+var defaultTLSNextProtos = []string{"h2", "http/1.1"}
+
 var (
 	// DefaultAliveCheck return always live.
 	DefaultAliveCheck = func() AliveStatus { return Alive }
@@ -28,30 +37,34 @@ var (
 
 // Component implementation of HTTP.
 type Component struct {
-	ac               AliveCheckFunc
-	rc               ReadyCheckFunc
-	httpPort         int
-	httpReadTimeout  time.Duration
-	httpWriteTimeout time.Duration
-	info             map[string]interface{}
+	ac                  AliveCheckFunc
+	rc                  ReadyCheckFunc
+	httpPort            int
+	httpReadTimeout     time.Duration
+	httpWriteTimeout    time.Duration
+	shutdownGracePeriod time.Duration
+	info                map[string]interface{}
 	sync.Mutex
-	routes      []Route
-	middlewares []MiddlewareFunc
-	certFile    string
-	keyFile     string
+	routes        []Route
+	middlewares   []MiddlewareFunc
+	certFile      string
+	keyFile       string
+	tlsNextProtos []string
+	listener      net.Listener
 }
 
 // New returns a new component.
 func New(oo ...OptionFunc) (*Component, error) {
 	c := Component{
-		ac:               DefaultAliveCheck,
-		rc:               DefaultReadyCheck,
-		httpPort:         httpPort,
-		httpReadTimeout:  httpReadTimeout,
-		httpWriteTimeout: httpWriteTimeout,
-		routes:           []Route{},
-		middlewares:      []MiddlewareFunc{},
-		info:             make(map[string]interface{}),
+		ac:                  DefaultAliveCheck,
+		rc:                  DefaultReadyCheck,
+		httpPort:            httpPort,
+		httpReadTimeout:     httpReadTimeout,
+		httpWriteTimeout:    httpWriteTimeout,
+		shutdownGracePeriod: httpShutdownGracePeriod,
+		routes:              []Route{},
+		middlewares:         []MiddlewareFunc{},
+		info:                make(map[string]interface{}),
 	}
 
 	for _, o := range oo {
@@ -81,7 +94,9 @@ func (c *Component) Run(ctx context.Context) error {
 	select {
 	case <-ctx.Done():
 		log.Info("shutting down component")
-		return srv.Shutdown(ctx)
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), c.shutdownGracePeriod)
+		defer cancel()
+		return srv.Shutdown(shutdownCtx)
 	case err := <-chFail:
 		return err
 	}
@@ -90,10 +105,19 @@ func (c *Component) Run(ctx context.Context) error {
 func (c *Component) listenAndServe(srv *http.Server, ch chan<- error) {
 	if c.certFile != "" && c.keyFile != "" {
 		log.Infof("HTTPS component listening on port %d", c.httpPort)
+		if c.listener != nil {
+			ch <- srv.ServeTLS(c.listener, c.certFile, c.keyFile)
+			return
+		}
 		ch <- srv.ListenAndServeTLS(c.certFile, c.keyFile)
+		return
 	}
 
 	log.Infof("HTTP component listening on port %d", c.httpPort)
+	if c.listener != nil {
+		ch <- srv.Serve(c.listener)
+		return
+	}
 	ch <- srv.ListenAndServe()
 }
 
@@ -101,26 +125,57 @@ func (c *Component) createHTTPServer() *http.Server {
 	log.Debugf("adding %d routes", len(c.routes))
 	router := httprouter.New()
 	for _, route := range c.routes {
-		if len(route.Middlewares) > 0 {
-			h := MiddlewareChain(route.Handler, route.Middlewares...)
-			router.Handler(route.Method, route.Pattern, h)
-		} else {
-			router.HandlerFunc(route.Method, route.Pattern, route.Handler)
-		}
-
+		router.Handler(route.Method, route.Pattern, routeHandler(route))
 		log.Debugf("added route %s %s", route.Method, route.Pattern)
 	}
-	// Add first the recovery middleware to ensure that no panic occur.
+	// Add first the recovery middleware to ensure that no panic occur. It
+	// wraps the whole router, rather than each route, so it also covers
+	// panics in httprouter's own not-found/method-not-allowed handling.
 	routerAfterMiddleware := MiddlewareChain(router, NewRecoveryMiddleware())
 	routerAfterMiddleware = MiddlewareChain(routerAfterMiddleware, c.middlewares...)
 
+	var handler http.Handler = routerAfterMiddleware
+	var tlsConfig *tls.Config
+	if c.certFile != "" && c.keyFile != "" {
+		nextProtos := c.tlsNextProtos
+		if len(nextProtos) == 0 {
+			nextProtos = defaultTLSNextProtos
+		}
+		tlsConfig = &tls.Config{NextProtos: nextProtos}
+	} else {
+		// No TLS configured: still offer HTTP/2 in cleartext (h2c) to clients
+		// that support it, instead of falling back to HTTP/1.1 only.
+		handler = h2c.NewHandler(routerAfterMiddleware, &http2.Server{})
+	}
+
 	return &http.Server{
 		Addr:         fmt.Sprintf(":%d", c.httpPort),
 		ReadTimeout:  c.httpReadTimeout,
 		WriteTimeout: c.httpWriteTimeout,
 		IdleTimeout:  httpIdleTimeout,
-		Handler:      routerAfterMiddleware,
+		Handler:      handler,
+		TLSConfig:    tlsConfig,
+	}
+}
+
+// routeHandler composes route's middlewares around its Handler, in a fixed
+// order: access-log/tracing, then auth, then rate-limit, then the route's
+// own Middlewares. Recovery wraps the whole server in createHTTPServer, so
+// it isn't repeated per route.
+func routeHandler(route Route) http.Handler {
+	var mm []MiddlewareFunc
+	if route.Trace {
+		mm = append(mm, NewLoggingTracingMiddleware(route.Pattern))
+	}
+	if route.Auth != nil {
+		mm = append(mm, NewAuthMiddleware(route.Auth))
 	}
+	if route.RateLimit != nil {
+		mm = append(mm, NewRateLimitMiddleware(route.Pattern, route.RateLimit))
+	}
+	mm = append(mm, route.Middlewares...)
+
+	return MiddlewareChain(route.Handler, mm...)
 }
 
 // 2019-12-05 start working on issue #54
@@ -144,51 +199,142 @@ func (cb *Builder) New2() *Builder {
 	}
 }
 
-// WithSSL ...
+// WithSSL sets the certificate and key files the component serves HTTPS with.
 func (cb *Builder) WithSSL(c, k string) *Builder {
+	if c == "" || k == "" {
+		cb.errors = append(cb.errors, errors.New("Invalid cert or key provided"))
+		return cb
+	}
+
+	log.Debug(propSetMsg, "ssl", "builder")
+	cb.certFile = c
+	cb.keyFile = k
+	return cb
+}
+
+// WithTLSNextProtos overrides the ALPN protocols advertised on a TLS
+// listener. It only has an effect together with WithSSL, and defaults to
+// {"h2", "http/1.1"} when not set.
+func (cb *Builder) WithTLSNextProtos(protos []string) *Builder {
+	if len(protos) == 0 {
+		cb.errors = append(cb.errors, errors.New("Empty NextProtos slice provided"))
+		return cb
+	}
 
+	log.Debug(propSetMsg, "tls next protos", "builder")
+	cb.tlsNextProtos = protos
 	return cb
 }
 
-// WithRoutes ...
+// WithRoutes sets the routes of the service.
 func (cb *Builder) WithRoutes(rr []Route) *Builder {
+	if len(rr) == 0 {
+		cb.errors = append(cb.errors, errors.New("Empty Routes slice provided"))
+		return cb
+	}
 
+	log.Debug(propSetMsg, "routes", "builder")
+	cb.routes = rr
 	return cb
 }
 
-// WithMiddlewares ...
+// WithMiddlewares sets the middlewares of the service.
 func (cb *Builder) WithMiddlewares(mm ...MiddlewareFunc) *Builder {
+	if len(mm) == 0 {
+		cb.errors = append(cb.errors, errors.New("Empty list of middlewares provided"))
+		return cb
+	}
 
+	log.Debug(propSetMsg, "middlewares", "builder")
+	cb.middlewares = mm
 	return cb
 }
 
-// WithReadTimeout ...
+// WithReadTimeout sets the read timeout of the service.
 func (cb *Builder) WithReadTimeout(rt time.Duration) *Builder {
+	if rt <= 0 {
+		cb.errors = append(cb.errors, errors.New("Negative or zero read timeout provided"))
+		return cb
+	}
 
+	log.Debug(propSetMsg, "read timeout", "builder")
+	cb.httpReadTimeout = rt
 	return cb
 }
 
-// WithWriteTimeout ...
+// WithWriteTimeout sets the write timeout of the service.
 func (cb *Builder) WithWriteTimeout(wt time.Duration) *Builder {
+	if wt <= 0 {
+		cb.errors = append(cb.errors, errors.New("Negative or zero write timeout provided"))
+		return cb
+	}
+
+	log.Debug(propSetMsg, "write timeout", "builder")
+	cb.httpWriteTimeout = wt
+	return cb
+}
+
+// WithShutdownGracePeriod sets how long Run waits, after its context is
+// cancelled, for in-flight requests to finish before Shutdown forces them
+// closed. It defaults to httpShutdownGracePeriod.
+func (cb *Builder) WithShutdownGracePeriod(d time.Duration) *Builder {
+	if d <= 0 {
+		cb.errors = append(cb.errors, errors.New("Negative or zero shutdown grace period provided"))
+		return cb
+	}
 
+	log.Debug(propSetMsg, "shutdown grace period", "builder")
+	cb.shutdownGracePeriod = d
 	return cb
 }
 
-// WithPort ...
+// WithListener sets a pre-bound listener for the component to serve on
+// instead of binding httpPort itself, e.g. for an ephemeral ":0" port in
+// tests or systemd socket activation.
+func (cb *Builder) WithListener(l net.Listener) *Builder {
+	if l == nil {
+		cb.errors = append(cb.errors, errors.New("Nil listener provided"))
+		return cb
+	}
+
+	log.Debug(propSetMsg, "listener", "builder")
+	cb.listener = l
+	return cb
+}
+
+// WithPort sets the port of the service.
 func (cb *Builder) WithPort(p int) *Builder {
+	if p <= 0 {
+		cb.errors = append(cb.errors, errors.New("Invalid HTTP Port provided"))
+		return cb
+	}
 
+	log.Debug(propSetMsg, "port", "builder")
+	cb.httpPort = p
 	return cb
 }
 
-// WithAliveCheckFunc ...
+// WithAliveCheckFunc sets the alive check function of the service.
 func (cb *Builder) WithAliveCheckFunc(acf AliveCheckFunc) *Builder {
+	if acf == nil {
+		cb.errors = append(cb.errors, errors.New("Nil AliveCheckFunc was provided"))
+		return cb
+	}
 
+	log.Debug(propSetMsg, "alive check function", "builder")
+	cb.ac = acf
 	return cb
 }
 
-// WithReadyCheckFunc ...
+// WithReadyCheckFunc sets the ready check function of the service.
 func (cb *Builder) WithReadyCheckFunc(rcf ReadyCheckFunc) *Builder {
+	if rcf == nil {
+		cb.errors = append(cb.errors, errors.New("Nil ReadyCheckFunc provided"))
+		return cb
+	}
 
+	log.Debug(propSetMsg, "ready check function", "builder")
+	cb.rc = rcf
 	return cb
 }
 
@@ -199,15 +345,22 @@ func (cb *Builder) Create() (*Component, error) {
 	}
 
 	c := &Component{
-		ac:               cb.ac,
-		rc:               cb.rc,
-		httpPort:         cb.httpPort,
-		httpReadTimeout:  cb.httpReadTimeout,
-		httpWriteTimeout: cb.httpWriteTimeout,
-		routes:           cb.routes,
-		middlewares:      cb.middlewares,
-		certFile:         cb.certFile,
-		keyFile:          cb.keyFile,
+		ac:                  cb.ac,
+		rc:                  cb.rc,
+		httpPort:            cb.httpPort,
+		httpReadTimeout:     cb.httpReadTimeout,
+		httpWriteTimeout:    cb.httpWriteTimeout,
+		shutdownGracePeriod: cb.shutdownGracePeriod,
+		routes:              cb.routes,
+		middlewares:         cb.middlewares,
+		certFile:            cb.certFile,
+		keyFile:             cb.keyFile,
+		tlsNextProtos:       cb.tlsNextProtos,
+		listener:            cb.listener,
+	}
+
+	if c.shutdownGracePeriod <= 0 {
+		c.shutdownGracePeriod = httpShutdownGracePeriod
 	}
 
 	return c, nil
@@ -0,0 +1,26 @@
+package http
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var countRateLimitAllowed *prometheus.CounterVec
+var countRateLimitThrottled *prometheus.CounterVec
+
+func init() {
+	countRateLimitAllowed = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "component",
+			Subsystem: "http",
+			Name:      "rate_limit_allowed",
+			Help:      "Requests let through a NewRateLimitMiddleware, classified by route",
+		}, []string{"route"},
+	)
+	countRateLimitThrottled = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "component",
+			Subsystem: "http",
+			Name:      "rate_limit_throttled",
+			Help:      "Requests rejected with 429 by a NewRateLimitMiddleware, classified by route",
+		}, []string{"route"},
+	)
+	prometheus.MustRegister(countRateLimitAllowed, countRateLimitThrottled)
+}
@@ -1,20 +1,28 @@
 package http
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"math/rand"
+	"net"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/beatlabs/patron/correlation"
 	"github.com/beatlabs/patron/log"
+	"github.com/beatlabs/patron/sync/http/apierror"
 	"github.com/beatlabs/patron/sync/http/auth"
 	traceHTTP "github.com/beatlabs/patron/trace/http"
 	"github.com/google/uuid"
+	opentracing "github.com/opentracing/opentracing-go"
 )
 
 type responseWriter struct {
 	status              int
 	statusHeaderWritten bool
+	bytesWritten        int
 	writer              http.ResponseWriter
 }
 
@@ -27,6 +35,11 @@ func (w *responseWriter) Status() int {
 	return w.status
 }
 
+// BytesWritten returns the number of response body bytes written so far.
+func (w *responseWriter) BytesWritten() int {
+	return w.bytesWritten
+}
+
 // Header returns the header.
 func (w *responseWriter) Header() http.Header {
 	return w.writer.Header()
@@ -36,6 +49,7 @@ func (w *responseWriter) Header() http.Header {
 func (w *responseWriter) Write(d []byte) (int, error) {
 
 	value, err := w.writer.Write(d)
+	w.bytesWritten += value
 	if err != nil {
 		return value, err
 	}
@@ -63,9 +77,9 @@ func NewRecoveryMiddleware() MiddlewareFunc {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			defer func() {
-				if r := recover(); r != nil {
+				if rec := recover(); rec != nil {
 					var err error
-					switch x := r.(type) {
+					switch x := rec.(type) {
 					case string:
 						err = errors.New(x)
 					case error:
@@ -73,9 +87,30 @@ func NewRecoveryMiddleware() MiddlewareFunc {
 					default:
 						err = errors.New("unknown panic")
 					}
-					_ = err
 					log.Errorf("recovering from an error %v", err)
-					http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+					apierror.WriteError(w, r, apierror.Internal())
+				}
+			}()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// NewErrorMiddleware creates a MiddlewareFunc that recovers an
+// *apierror.APIError panic and writes it via apierror.WriteError as a
+// consistent JSON/problem+json body. Unlike
+// NewRecoveryMiddleware, a non-APIError panic is still re-raised, so a
+// RecoveryMiddleware further down the chain is still required.
+func NewErrorMiddleware() MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				if rec := recover(); rec != nil {
+					apiErr, ok := rec.(*apierror.APIError)
+					if !ok {
+						panic(rec)
+					}
+					apierror.WriteError(w, r, apiErr)
 				}
 			}()
 			next.ServeHTTP(w, r)
@@ -102,14 +137,52 @@ func NewAuthMiddleware(auth auth.Authenticator) MiddlewareFunc {
 	}
 }
 
+// statusWriterKey is the context key NewAccessLogMiddleware publishes its
+// *responseWriter under, so NewLoggingTracingMiddleware, when chained
+// behind it, reads the final status from the same writer instead of
+// wrapping w a second time.
+type statusWriterKey struct{}
+
+func statusWriterFromContext(ctx context.Context) (*responseWriter, bool) {
+	w, ok := ctx.Value(statusWriterKey{}).(*responseWriter)
+	return w, ok
+}
+
+// spanHolderKey is the context key NewAccessLogMiddleware publishes a
+// *spanHolder under, so NewLoggingTracingMiddleware, when chained behind
+// it, can hand back the span it started, letting the access log record
+// the request's trace ID.
+type spanHolderKey struct{}
+
+// spanHolder is a mutable box for the span NewLoggingTracingMiddleware
+// starts after NewAccessLogMiddleware has already set up the request
+// context, so it can be read back once NewAccessLogMiddleware's call to
+// next.ServeHTTP returns.
+type spanHolder struct {
+	sp opentracing.Span
+}
+
+func spanHolderFromContext(ctx context.Context) (*spanHolder, bool) {
+	h, ok := ctx.Value(spanHolderKey{}).(*spanHolder)
+	return h, ok
+}
+
 // NewLoggingTracingMiddleware creates a MiddlewareFunc that continues a tracing span and finishes it.
-// It also logs the HTTP request on debug logging level
+// It also logs the HTTP request on debug logging level. If it is chained behind a
+// NewAccessLogMiddleware, it reuses that middleware's status writer and publishes its span into
+// its span holder instead of wrapping w and tracking the status a second time.
 func NewLoggingTracingMiddleware(path string) MiddlewareFunc {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			corID := getOrSetCorrelationID(r.Header)
 			sp, r := traceHTTP.Span(path, corID, r)
-			lw := newResponseWriter(w)
+			if sh, ok := spanHolderFromContext(r.Context()); ok {
+				sh.sp = sp
+			}
+			lw, ok := statusWriterFromContext(r.Context())
+			if !ok {
+				lw = newResponseWriter(w)
+			}
 			next.ServeHTTP(lw, r)
 			traceHTTP.FinishHTTPSpan(sp, lw.Status())
 			logRequestResponse(lw, r)
@@ -117,6 +190,190 @@ func NewLoggingTracingMiddleware(path string) MiddlewareFunc {
 	}
 }
 
+// AccessLogRecord is the structured record NewAccessLogMiddleware logs for
+// a sampled request.
+type AccessLogRecord struct {
+	Method        string
+	Route         string
+	RemoteIP      string
+	Status        int
+	DurationMs    float64
+	BytesWritten  int
+	CorrelationID string
+	TraceID       string
+}
+
+// accessLogConfig holds the options gathered by NewAccessLogMiddleware.
+type accessLogConfig struct {
+	trustedProxies []*net.IPNet
+	sampleRate     float64
+	slowThreshold  time.Duration
+}
+
+// AccessLogOption configures NewAccessLogMiddleware.
+type AccessLogOption func(*accessLogConfig) error
+
+// WithTrustedProxies restricts which upstream hops NewAccessLogMiddleware
+// trusts to report the client's address via X-Forwarded-For/X-Real-IP:
+// those headers are only consulted when r.RemoteAddr itself falls within
+// one of cidrs (e.g. a load balancer's known subnet), so a direct,
+// untrusted client can't spoof its own logged IP. Without
+// WithTrustedProxies, RemoteAddr is always used as-is.
+func WithTrustedProxies(cidrs ...string) AccessLogOption {
+	return func(c *accessLogConfig) error {
+		nets := make([]*net.IPNet, 0, len(cidrs))
+		for _, cidr := range cidrs {
+			_, n, err := net.ParseCIDR(cidr)
+			if err != nil {
+				return errors.New("invalid trusted proxy CIDR " + cidr + ": " + err.Error())
+			}
+			nets = append(nets, n)
+		}
+		c.trustedProxies = nets
+		return nil
+	}
+}
+
+// WithSampleRate logs only a fraction of successful (< 500) requests
+// faster than WithSlowThreshold, to cut log volume on high-traffic routes.
+// rate must be in [0, 1]; it defaults to 1, logging every request. Error
+// responses, and ones slower than WithSlowThreshold, are always logged
+// regardless of rate.
+func WithSampleRate(rate float64) AccessLogOption {
+	return func(c *accessLogConfig) error {
+		if rate < 0 || rate > 1 {
+			return errors.New("sample rate must be between 0 and 1")
+		}
+		c.sampleRate = rate
+		return nil
+	}
+}
+
+// WithSlowThreshold always logs a request whose handler took at least d,
+// regardless of its status code or WithSampleRate.
+func WithSlowThreshold(d time.Duration) AccessLogOption {
+	return func(c *accessLogConfig) error {
+		if d <= 0 {
+			return errors.New("slow threshold must be greater than 0")
+		}
+		c.slowThreshold = d
+		return nil
+	}
+}
+
+// NewAccessLogMiddleware creates a MiddlewareFunc that logs one structured
+// AccessLogRecord per sampled request: method, route (path, the pattern
+// the route was registered under, not r.URL, so templated routes like
+// /users/:id aggregate in log pipelines instead of fragmenting per ID),
+// remote IP, status, bytes written, duration, correlation ID, and the
+// current span's trace ID if a NewLoggingTracingMiddleware is chained
+// behind it. Every 5xx response, and every request slower than
+// WithSlowThreshold, is always logged; everything else is logged at
+// WithSampleRate's rate. It also publishes its status writer and a span
+// holder on the request context, so a NewLoggingTracingMiddleware chained
+// behind it shares both instead of wrapping w and tracking the status a
+// second time.
+func NewAccessLogMiddleware(path string, oo ...AccessLogOption) (MiddlewareFunc, error) {
+	c := &accessLogConfig{sampleRate: 1}
+	for _, o := range oo {
+		if err := o(c); err != nil {
+			return nil, err
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			corID := getOrSetCorrelationID(r.Header)
+			lw := newResponseWriter(w)
+			sh := &spanHolder{}
+			ctx := context.WithValue(r.Context(), statusWriterKey{}, lw)
+			ctx = context.WithValue(ctx, spanHolderKey{}, sh)
+			r = r.WithContext(ctx)
+
+			start := time.Now()
+			next.ServeHTTP(lw, r)
+			duration := time.Since(start)
+
+			if !c.shouldLog(lw.Status(), duration) {
+				return
+			}
+
+			rec := AccessLogRecord{
+				Method:        r.Method,
+				Route:         path,
+				RemoteIP:      c.remoteIP(r),
+				Status:        lw.Status(),
+				DurationMs:    float64(duration.Microseconds()) / 1000,
+				BytesWritten:  lw.BytesWritten(),
+				CorrelationID: corID,
+				TraceID:       traceID(sh),
+			}
+			log.Sub(map[string]interface{}{"access": rec}).Info()
+		})
+	}, nil
+}
+
+// shouldLog applies NewAccessLogMiddleware's sampling rules: always log
+// errors and slow requests, otherwise roll the dice at c.sampleRate.
+func (c *accessLogConfig) shouldLog(status int, duration time.Duration) bool {
+	if status >= http.StatusInternalServerError {
+		return true
+	}
+	if c.slowThreshold > 0 && duration >= c.slowThreshold {
+		return true
+	}
+	if c.sampleRate >= 1 {
+		return true
+	}
+	if c.sampleRate <= 0 {
+		return false
+	}
+	return rand.Float64() < c.sampleRate
+}
+
+// remoteIP resolves r's client address, trusting X-Forwarded-For/
+// X-Real-IP only when r.RemoteAddr matches one of c.trustedProxies.
+func (c *accessLogConfig) remoteIP(r *http.Request) string {
+	addr := remoteAddress(r)
+	if !c.fromTrustedProxy(addr) {
+		return addr
+	}
+
+	if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+		if first, _, ok := strings.Cut(fwd, ","); ok {
+			return strings.TrimSpace(first)
+		}
+		return strings.TrimSpace(fwd)
+	}
+	if real := r.Header.Get("X-Real-IP"); real != "" {
+		return real
+	}
+	return addr
+}
+
+func (c *accessLogConfig) fromTrustedProxy(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, n := range c.trustedProxies {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// traceID returns the trace ID of the span NewLoggingTracingMiddleware
+// published into sh, or "" if no tracing middleware is chained behind
+// NewAccessLogMiddleware.
+func traceID(sh *spanHolder) string {
+	if sh == nil || sh.sp == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v", sh.sp.Context())
+}
+
 // MiddlewareChain chains middlewares to a handler func.
 func MiddlewareChain(f http.Handler, mm ...MiddlewareFunc) http.Handler {
 	for i := len(mm) - 1; i >= 0; i-- {
@@ -130,14 +387,9 @@ func logRequestResponse(w *responseWriter, r *http.Request) {
 		return
 	}
 
-	remoteAddr := r.RemoteAddr
-	if i := strings.LastIndex(remoteAddr, ":"); i != -1 {
-		remoteAddr = remoteAddr[:i]
-	}
-
 	info := map[string]interface{}{
 		"request": map[string]interface{}{
-			"remote-address": remoteAddr,
+			"remote-address": remoteAddress(r),
 			"method":         r.Method,
 			"url":            r.URL,
 			"proto":          r.Proto,
@@ -149,6 +401,15 @@ func logRequestResponse(w *responseWriter, r *http.Request) {
 	log.Sub(info).Debug()
 }
 
+// remoteAddress returns r.RemoteAddr with any trailing port stripped.
+func remoteAddress(r *http.Request) string {
+	remoteAddr := r.RemoteAddr
+	if i := strings.LastIndex(remoteAddr, ":"); i != -1 {
+		remoteAddr = remoteAddr[:i]
+	}
+	return remoteAddr
+}
+
 func getOrSetCorrelationID(h http.Header) string {
 	cor, ok := h[correlation.HeaderID]
 	if !ok {
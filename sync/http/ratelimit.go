@@ -0,0 +1,124 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/beatlabs/patron/cache/lru"
+	"golang.org/x/time/rate"
+)
+
+// defaultRateLimiterCacheSize bounds how many distinct keys a keyedLimiter
+// keeps a *rate.Limiter for. Without a bound, a KeyFunc with effectively
+// unlimited cardinality - e.g. CorrelationIDKeyFunc against clients that
+// never send one - would grow the limiter set for the lifetime of the
+// process, turning the rate limiter itself into an unbounded-memory DoS
+// vector.
+const defaultRateLimiterCacheSize = 10_000
+
+// KeyFunc extracts the key a rate limit buckets requests by, e.g. the
+// client IP, a correlation ID, or an authenticated principal.
+type KeyFunc func(r *http.Request) string
+
+// ClientIPKeyFunc returns a KeyFunc keying by the request's remote IP,
+// resolved the same way NewAccessLogMiddleware does: trusting
+// X-Forwarded-For/X-Real-IP only when r.RemoteAddr falls within one of
+// trustedProxies. It is the default key function used by WithRateLimit.
+func ClientIPKeyFunc(trustedProxies ...string) (KeyFunc, error) {
+	c := &accessLogConfig{}
+	if err := WithTrustedProxies(trustedProxies...)(c); err != nil {
+		return nil, err
+	}
+	return c.remoteIP, nil
+}
+
+// CorrelationIDKeyFunc keys by the request's correlation ID, generating one
+// via getOrSetCorrelationID if the caller didn't send one.
+func CorrelationIDKeyFunc(r *http.Request) string {
+	return getOrSetCorrelationID(r.Header)
+}
+
+// RateLimitConfig holds a route's rate limit, gathered by Route.WithRateLimit.
+type RateLimitConfig struct {
+	rps     rate.Limit
+	burst   int
+	keyFunc KeyFunc
+}
+
+// RateLimitOption configures a RateLimitConfig gathered by WithRateLimit.
+type RateLimitOption func(*RateLimitConfig)
+
+// WithRateLimitKeyFunc overrides the default ClientIPKeyFunc() key
+// function a rate limit buckets requests by.
+func WithRateLimitKeyFunc(kf KeyFunc) RateLimitOption {
+	return func(c *RateLimitConfig) {
+		c.keyFunc = kf
+	}
+}
+
+func newRateLimitConfig(rps float64, burst int, oo ...RateLimitOption) *RateLimitConfig {
+	defaultKeyFunc, _ := ClientIPKeyFunc()
+	c := &RateLimitConfig{rps: rate.Limit(rps), burst: burst, keyFunc: defaultKeyFunc}
+	for _, o := range oo {
+		o(c)
+	}
+	return c
+}
+
+// keyedLimiter hands out a *rate.Limiter per key, creating one on first use.
+// limiters is bounded by defaultRateLimiterCacheSize, evicting the
+// least-recently-used key's limiter once it fills up, so an unbounded
+// KeyFunc can't grow it without limit.
+type keyedLimiter struct {
+	mu       sync.Mutex
+	rps      rate.Limit
+	burst    int
+	limiters *lru.Cache
+}
+
+func newKeyedLimiter(rps rate.Limit, burst int) *keyedLimiter {
+	limiters, _ := lru.New(defaultRateLimiterCacheSize)
+	return &keyedLimiter{rps: rps, burst: burst, limiters: limiters}
+}
+
+func (l *keyedLimiter) limiterFor(key string) *rate.Limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if v, ok, _ := l.limiters.Get(key); ok {
+		return v.(*rate.Limiter)
+	}
+	lim := rate.NewLimiter(l.rps, l.burst)
+	_ = l.limiters.Set(key, lim)
+	return lim
+}
+
+// NewRateLimitMiddleware creates a MiddlewareFunc enforcing cfg on route:
+// a golang.org/x/time/rate.Limiter per cfg.keyFunc key, allowing cfg.rps
+// requests per second with bursts of up to cfg.burst. A request that would
+// exceed the limit gets a 429 with Retry-After set to roughly how long
+// until a token frees up, and is not passed to next. Allowed and throttled
+// requests are counted in Prometheus, classified by route.
+func NewRateLimitMiddleware(route string, cfg *RateLimitConfig) MiddlewareFunc {
+	l := newKeyedLimiter(cfg.rps, cfg.burst)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			res := l.limiterFor(cfg.keyFunc(r)).Reserve()
+			delay := res.Delay()
+			if !res.OK() || delay > 0 {
+				res.Cancel()
+				if delay > 0 {
+					w.Header().Set("Retry-After", strconv.Itoa(int(delay.Seconds()+1)))
+				}
+				http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+				countRateLimitThrottled.WithLabelValues(route).Inc()
+				return
+			}
+
+			countRateLimitAllowed.WithLabelValues(route).Inc()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -10,12 +10,30 @@ import (
 
 // Route definition of a HTTP route.
 type Route struct {
-	Pattern   string
-	Method    string
-	Handler   http.HandlerFunc
-	Trace     bool
-	Auth      auth.Authenticator
-	MediaType []encoding.MediaType
+	Pattern     string
+	Method      string
+	Handler     http.HandlerFunc
+	Trace       bool
+	Auth        auth.Authenticator
+	MediaType   []encoding.MediaType
+	Middlewares []MiddlewareFunc
+	RateLimit   *RateLimitConfig
+}
+
+// WithMiddlewares returns a copy of r with mm appended after the route's
+// auth and rate-limit middlewares, and before its Handler.
+func (r Route) WithMiddlewares(mm ...MiddlewareFunc) Route {
+	r.Middlewares = append(r.Middlewares, mm...)
+	return r
+}
+
+// WithRateLimit returns a copy of r rate-limited to rps requests per
+// second per key, with bursts of up to burst requests. Requests are keyed
+// by ClientIPKeyFunc() unless overridden with WithRateLimitKeyFunc.
+func (r Route) WithRateLimit(rps float64, burst int, oo ...RateLimitOption) Route {
+	cfg := newRateLimitConfig(rps, burst, oo...)
+	r.RateLimit = cfg
+	return r
 }
 
 // NewGetRoute creates a new GET route from a generic handler.
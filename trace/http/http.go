@@ -2,10 +2,14 @@ package http
 
 import (
 	"context"
+	goerrors "errors"
+	"math/rand"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/beatlabs/patron/correlation"
+	"github.com/beatlabs/patron/errors"
 	"github.com/beatlabs/patron/log"
 	"github.com/beatlabs/patron/reliability/circuitbreaker"
 	"github.com/opentracing-contrib/go-stdlib/nethttp"
@@ -21,6 +25,20 @@ const (
 
 	versionTag = "version"
 	hostsTag   = "hosts"
+
+	// httpRouteTag and netPeerNameTag name OTel semconv HTTP attributes that
+	// have no opentracing-go/ext equivalent. They are set as plain string
+	// tags rather than through ext so that a trace/otel.Provider's bridge
+	// reports them under their semconv names even though the span API here
+	// stays opentracing.Span.
+	httpRouteTag   = "http.route"
+	netPeerNameTag = "net.peer.name"
+
+	// retryCountTag tags each retry attempt's child span with how many
+	// retries preceded it (1 for the first retry, i.e. the second attempt
+	// overall), so a trace viewer can tell the retry pattern apart from a
+	// single slow request.
+	retryCountTag = "http.retry_count"
 )
 
 var (
@@ -32,10 +50,112 @@ type Client interface {
 	Do(ctx context.Context, req *http.Request) (*http.Response, error)
 }
 
+// OptionFunc configures a TracedClient created via New.
+type OptionFunc func(*TracedClient) error
+
+// idempotentContextKey is the context key WithIdempotent sets to mark a
+// request safe to retry even though its HTTP method isn't inherently
+// idempotent (e.g. POST, PATCH).
+type idempotentContextKey struct{}
+
+// WithIdempotent marks ctx so a request made with it is retried by
+// WithRetry the same as a GET/PUT/DELETE, even if its method is POST or
+// PATCH. Only set this when the caller knows resending the request is
+// safe, e.g. it carries its own idempotency key upstream.
+func WithIdempotent(ctx context.Context) context.Context {
+	return context.WithValue(ctx, idempotentContextKey{}, true)
+}
+
+func isIdempotent(ctx context.Context, method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	marked, _ := ctx.Value(idempotentContextKey{}).(bool)
+	return marked
+}
+
+// JitterMode selects how WithRetry spreads its backoff delay between
+// attempts, to avoid every client retrying in lockstep against a struggling
+// upstream.
+type JitterMode int
+
+const (
+	// JitterFull picks a delay uniformly between 0 and the full backoff.
+	JitterFull JitterMode = iota
+	// JitterEqual picks a delay uniformly between half the backoff and the
+	// full backoff, trading some thundering-herd protection for a delay
+	// that never drops all the way to 0.
+	JitterEqual
+	// JitterDecorrelated picks a delay uniformly between RetryPolicy.BaseDelay
+	// and three times the previous delay, per AWS's "Exponential Backoff And
+	// Jitter" decorrelated jitter algorithm.
+	JitterDecorrelated
+)
+
+// RetryPolicy configures WithRetry.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first,
+	// before giving up. It must be at least 1.
+	MaxAttempts int
+	// BaseDelay seeds the backoff: the delay before the second attempt is
+	// drawn by Jitter from twice BaseDelay (from BaseDelay up to three times
+	// it under JitterDecorrelated). It is also the floor JitterDecorrelated
+	// ever picks.
+	BaseDelay time.Duration
+	// MaxDelay caps the backoff delay between any two attempts.
+	MaxDelay time.Duration
+	// Jitter selects how the backoff delay between attempts is randomized.
+	Jitter JitterMode
+	// Retryable decides whether a response/error pair should be retried.
+	// It defaults to DefaultRetryable.
+	Retryable func(*http.Response, error) bool
+}
+
+// DefaultRetryable retries network errors (err != nil) and 429, 502, 503,
+// and 504 responses.
+func DefaultRetryable(rsp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch rsp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// WithRetry enables retrying failed requests per policy. Only idempotent
+// methods (GET, HEAD, PUT, DELETE, OPTIONS, TRACE) are retried unless the
+// request's context was marked with WithIdempotent. Retries never bypass
+// the client's circuit breaker: an open-breaker error stops the retry loop
+// immediately instead of burning through MaxAttempts against a breaker
+// that would reject every one of them anyway.
+func WithRetry(policy RetryPolicy) OptionFunc {
+	return func(tc *TracedClient) error {
+		if policy.MaxAttempts < 1 {
+			return errors.New("max attempts must be at least 1")
+		}
+		if policy.BaseDelay <= 0 {
+			return errors.New("base delay must be positive")
+		}
+		if policy.MaxDelay < policy.BaseDelay {
+			return errors.New("max delay must be greater or equal than base delay")
+		}
+		if policy.Retryable == nil {
+			policy.Retryable = DefaultRetryable
+		}
+		tc.retry = &policy
+		return nil
+	}
+}
+
 // TracedClient defines a HTTP client with tracing integrated.
 type TracedClient struct {
-	cl *http.Client
-	cb *circuitbreaker.CircuitBreaker
+	cl    *http.Client
+	cb    *circuitbreaker.CircuitBreaker
+	retry *RetryPolicy
 }
 
 // New creates a new HTTP client.
@@ -68,7 +188,7 @@ func (tc *TracedClient) Do(ctx context.Context, req *http.Request) (*http.Respon
 
 	req.Header.Set(correlation.HeaderID, correlation.IDFromContext(ctx))
 
-	rsp, err := tc.do(req)
+	rsp, err := tc.doWithRetry(ctx, req, ht.Span())
 	if err != nil {
 		ext.Error.Set(ht.Span(), true)
 	} else {
@@ -77,9 +197,143 @@ func (tc *TracedClient) Do(ctx context.Context, req *http.Request) (*http.Respon
 
 	ext.HTTPMethod.Set(ht.Span(), req.Method)
 	ext.HTTPUrl.Set(ht.Span(), req.URL.String())
+	ht.Span().SetTag(netPeerNameTag, req.URL.Hostname())
+	return rsp, err
+}
+
+// doWithRetry executes req, retrying per tc.retry if WithRetry was used and
+// req's method (or ctx, via WithIdempotent) allows it. Every attempt after
+// the first runs as a child span of parent, tagged with retryCountTag, so a
+// trace shows the retry pattern instead of one span covering every attempt.
+func (tc *TracedClient) doWithRetry(ctx context.Context, req *http.Request, parent opentracing.Span) (*http.Response, error) {
+	if tc.retry == nil || !isIdempotent(ctx, req.Method) {
+		return tc.do(req)
+	}
+
+	var rsp *http.Response
+	var err error
+	delay := tc.retry.BaseDelay
+
+	for attempt := 1; attempt <= tc.retry.MaxAttempts; attempt++ {
+		attemptReq := req
+		var sp opentracing.Span
+		if attempt > 1 {
+			attemptReq, err = rewindRequest(req)
+			if err != nil {
+				return nil, err
+			}
+			sp = opentracing.StartSpan(OpName(attemptReq.Method, attemptReq.URL.String()), opentracing.ChildOf(parent.Context()))
+			sp.SetTag(retryCountTag, attempt-1)
+			attemptReq = attemptReq.WithContext(opentracing.ContextWithSpan(attemptReq.Context(), sp))
+		}
+
+		rsp, err = tc.do(attemptReq)
+		if sp != nil {
+			if err != nil {
+				ext.Error.Set(sp, true)
+			} else {
+				ext.HTTPStatusCode.Set(sp, uint16(rsp.StatusCode))
+			}
+			sp.Finish()
+		}
+
+		if goerrors.Is(err, circuitbreaker.ErrOpen) {
+			return rsp, err
+		}
+		if !tc.retry.Retryable(rsp, err) || attempt == tc.retry.MaxAttempts {
+			return rsp, err
+		}
+
+		// rsp is being superseded by a retry and never reaches the caller,
+		// so close its body now or the underlying connection leaks.
+		if rsp != nil {
+			_ = rsp.Body.Close()
+		}
+
+		wait := retryAfter(rsp)
+		if wait <= 0 {
+			wait = backoffDelay(tc.retry, delay)
+		}
+		delay = wait
+
+		select {
+		case <-ctx.Done():
+			return rsp, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+
 	return rsp, err
 }
 
+// rewindRequest clones req with a fresh, unconsumed body for a retry
+// attempt, using req.GetBody to replay a body already read by a previous
+// attempt.
+func rewindRequest(req *http.Request) (*http.Request, error) {
+	clone := req.Clone(req.Context())
+	if req.Body == nil || req.Body == http.NoBody {
+		return clone, nil
+	}
+	if req.GetBody == nil {
+		return nil, errors.New("cannot retry request: body was already consumed and is not rewindable")
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to rewind request body for retry")
+	}
+	clone.Body = body
+	return clone, nil
+}
+
+// retryAfter honors a 429 or 503 response's Retry-After header, returning 0
+// if the response doesn't call for one.
+func retryAfter(rsp *http.Response) time.Duration {
+	if rsp == nil || (rsp.StatusCode != http.StatusTooManyRequests && rsp.StatusCode != http.StatusServiceUnavailable) {
+		return 0
+	}
+	v := rsp.Header.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
+
+// backoffDelay picks the next retry delay for policy's jitter mode. prev is
+// the delay picked for the previous attempt (or policy.BaseDelay before the
+// first retry).
+func backoffDelay(policy *RetryPolicy, prev time.Duration) time.Duration {
+	if policy.Jitter == JitterDecorrelated {
+		upper := prev * 3
+		if upper <= policy.BaseDelay {
+			upper = policy.BaseDelay + 1
+		}
+		d := policy.BaseDelay + time.Duration(rand.Int63n(int64(upper-policy.BaseDelay)))
+		if d > policy.MaxDelay {
+			d = policy.MaxDelay
+		}
+		return d
+	}
+
+	backoff := prev * 2
+	if backoff > policy.MaxDelay || backoff <= 0 {
+		backoff = policy.MaxDelay
+	}
+
+	switch policy.Jitter {
+	case JitterEqual:
+		half := backoff / 2
+		return half + time.Duration(rand.Int63n(int64(half)+1))
+	default: // JitterFull
+		return time.Duration(rand.Int63n(int64(backoff) + 1))
+	}
+}
+
 func (tc *TracedClient) do(req *http.Request) (*http.Response, error) {
 	if tc.cb == nil {
 		return tc.cl.Do(req)
@@ -105,6 +359,8 @@ func Span(path, corID string, r *http.Request) (opentracing.Span, *http.Request)
 	ext.HTTPMethod.Set(sp, r.Method)
 	ext.HTTPUrl.Set(sp, r.URL.String())
 	ext.Component.Set(sp, "http")
+	sp.SetTag(httpRouteTag, path)
+	sp.SetTag(netPeerNameTag, r.Host)
 	sp.SetTag(versionTag, version)
 	sp.SetTag(correlation.ID, corID)
 	return sp, r.WithContext(opentracing.ContextWithSpan(r.Context(), sp))
@@ -0,0 +1,288 @@
+// Package admin wraps sarama.ClusterAdmin with a small, well-typed surface for
+// managing Kafka topics and partition assignments from within a patron service.
+package admin
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Shopify/sarama"
+	"github.com/beatlabs/patron/errors"
+	"github.com/beatlabs/patron/log"
+	"github.com/beatlabs/patron/trace"
+	"github.com/opentracing/opentracing-go"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	adminComponent = "kafka-admin"
+
+	outcomeSuccess = "success"
+	outcomeError   = "error"
+)
+
+// minReassignmentVersion is the minimum Kafka protocol version that supports the
+// AlterPartitionReassignments/ListPartitionReassignments RPCs (KIP-455).
+var minReassignmentVersion = sarama.V2_4_0_0
+
+var countAdminOps *prometheus.CounterVec
+
+func init() {
+	countAdminOps = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "component",
+			Subsystem: "kafka_admin",
+			Name:      "operations",
+			Help:      "Admin operations counter, classified by operation and outcome",
+		}, []string{"operation", "outcome"},
+	)
+	prometheus.MustRegister(countAdminOps)
+}
+
+// Client wraps a sarama.ClusterAdmin to manage Kafka topics and partition assignments.
+type Client struct {
+	admin sarama.ClusterAdmin
+}
+
+// Builder gathers the required and optional properties to construct an admin Client,
+// mirroring the producer's Builder.
+type Builder struct {
+	brokers []string
+	cfg     *sarama.Config
+	errors  []error
+}
+
+// NewBuilder initiates the admin Client builder chain.
+func NewBuilder() *Builder {
+	cfg := sarama.NewConfig()
+	cfg.Version = minReassignmentVersion
+	return &Builder{cfg: cfg}
+}
+
+// WithBrokers sets the list of brokers the admin client will work with.
+func (b *Builder) WithBrokers(brokers []string) *Builder {
+	if len(brokers) == 0 {
+		b.errors = append(b.errors, errors.New("brokers list is empty"))
+	} else {
+		log.Info("Setting property '%v' for '%v'", "brokers", brokers)
+		b.brokers = append(b.brokers, brokers...)
+	}
+	return b
+}
+
+// WithVersion sets the kafka version for the admin client. It must be at least
+// 2.4.0, which is the first version that supports partition reassignment RPCs.
+func (b *Builder) WithVersion(version string) *Builder {
+	if version == "" {
+		b.errors = append(b.errors, errors.New("version is required"))
+		return b
+	}
+	v, err := sarama.ParseKafkaVersion(version)
+	if err != nil {
+		b.errors = append(b.errors, errors.New("failed to parse kafka version"))
+		return b
+	}
+	log.Info("Setting property '%v' for '%v'", "version", version)
+	b.cfg.Version = v
+	return b
+}
+
+// Create constructs the admin Client by applying the gathered properties.
+func (b *Builder) Create() (*Client, error) {
+	if len(b.errors) > 0 {
+		return nil, errors.Aggregate(b.errors...)
+	}
+	if !b.cfg.Version.IsAtLeast(minReassignmentVersion) {
+		return nil, fmt.Errorf("kafka version must be at least %s to support partition reassignment RPCs", minReassignmentVersion)
+	}
+
+	admin, err := sarama.NewClusterAdmin(b.brokers, b.cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create cluster admin")
+	}
+
+	return &Client{admin: admin}, nil
+}
+
+// Close releases the underlying admin connection.
+func (c *Client) Close() error {
+	return c.admin.Close()
+}
+
+// CreateTopic creates a topic with the given partition/replication configuration.
+func (c *Client) CreateTopic(topic string, detail *sarama.TopicDetail) error {
+	sp, _ := trace.ChildSpan(context.Background(), trace.ComponentOpName(adminComponent, topic), adminComponent,
+		opentracing.Tag{Key: "operation", Value: "create-topic"})
+	err := c.admin.CreateTopic(topic, detail, false)
+	return c.finish(sp, "create-topic", err)
+}
+
+// DeleteTopic deletes the given topic.
+func (c *Client) DeleteTopic(topic string) error {
+	sp, _ := trace.ChildSpan(context.Background(), trace.ComponentOpName(adminComponent, topic), adminComponent,
+		opentracing.Tag{Key: "operation", Value: "delete-topic"})
+	err := c.admin.DeleteTopic(topic)
+	return c.finish(sp, "delete-topic", err)
+}
+
+// ListTopics returns metadata for every topic on the cluster.
+func (c *Client) ListTopics() (map[string]sarama.TopicDetail, error) {
+	topics, err := c.admin.ListTopics()
+	c.record("list-topics", err)
+	return topics, err
+}
+
+// DescribeTopics returns metadata for the given topics.
+func (c *Client) DescribeTopics(topics ...string) ([]*sarama.TopicMetadata, error) {
+	md, err := c.admin.DescribeTopics(topics)
+	c.record("describe-topics", err)
+	return md, err
+}
+
+// CreatePartitions increases the partition count of a topic to count.
+func (c *Client) CreatePartitions(topic string, count int32) error {
+	err := c.admin.CreatePartitions(topic, count, nil, false)
+	c.record("create-partitions", err)
+	return err
+}
+
+// AlterPartitionReassignments submits a new replica assignment for each partition in
+// assignment, initiating a reassignment. Passing a nil replica list for a partition
+// cancels any reassignment in progress for it, per KIP-455 semantics.
+//
+// sarama's AlterPartitionReassignments takes blocks indexed by partition number and
+// submits a block - including an implicit cancel for a nil entry - for every index up
+// to len(blocks)-1, not just the partitions assignment sets. So a sparse assignment
+// (e.g. partitions 0 and 10 of an 11-partition topic) would otherwise submit spurious
+// cancellations for partitions 1-9. Any such gap is instead filled with that
+// partition's current replica set, a no-op resubmission, so reassignments the caller
+// never mentioned are left alone.
+func (c *Client) AlterPartitionReassignments(topic string, assignment map[int32][]int32) error {
+	if len(assignment) == 0 {
+		return nil
+	}
+
+	maxPartition := int32(-1)
+	for p := range assignment {
+		if p > maxPartition {
+			maxPartition = p
+		}
+	}
+
+	blocks := make([][]int32, maxPartition+1)
+	for p, replicas := range assignment {
+		blocks[p] = replicas
+	}
+
+	if hasGap(blocks, assignment) {
+		current, err := c.currentReplicas(topic)
+		if err != nil {
+			return err
+		}
+		fillUnmentionedPartitions(blocks, assignment, current)
+	}
+
+	err := c.admin.AlterPartitionReassignments(topic, blocks)
+	c.record("alter-partition-reassignments", err)
+	return err
+}
+
+// hasGap reports whether blocks contains an index assignment doesn't mention,
+// i.e. one AlterPartitionReassignments would otherwise submit as a cancel.
+func hasGap(blocks [][]int32, assignment map[int32][]int32) bool {
+	for p := range blocks {
+		if _, ok := assignment[int32(p)]; !ok {
+			return true
+		}
+	}
+	return false
+}
+
+// fillUnmentionedPartitions sets blocks[p] to current[p] for every index p that
+// assignment doesn't mention, so AlterPartitionReassignments resubmits those
+// partitions' existing replicas instead of cancelling them.
+func fillUnmentionedPartitions(blocks [][]int32, assignment map[int32][]int32, current map[int32][]int32) {
+	for p := range blocks {
+		if _, ok := assignment[int32(p)]; ok {
+			continue
+		}
+		blocks[p] = current[int32(p)]
+	}
+}
+
+// currentReplicas fetches topic's current per-partition replica assignment, used to
+// fill gaps AlterPartitionReassignments' caller left in a sparse assignment.
+func (c *Client) currentReplicas(topic string) (map[int32][]int32, error) {
+	md, err := c.DescribeTopics(topic)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe topic %s for partition reassignment: %w", topic, err)
+	}
+	if len(md) == 0 {
+		return nil, fmt.Errorf("topic %s not found", topic)
+	}
+
+	current := make(map[int32][]int32, len(md[0].Partitions))
+	for _, pm := range md[0].Partitions {
+		current[pm.ID] = pm.Replicas
+	}
+	return current, nil
+}
+
+// PartitionReassignment describes the in-progress reassignment of a single partition.
+type PartitionReassignment struct {
+	Topic     string
+	Partition int32
+	Adding    []int32
+	Removing  []int32
+}
+
+// ListPartitionReassignments returns the partitions of the given topics (all topics,
+// if none are given) that are still reassigning, along with their adding/removing replicas.
+func (c *Client) ListPartitionReassignments(topics ...string) ([]PartitionReassignment, error) {
+	var topicPartitions map[string][]int32
+	if len(topics) > 0 {
+		topicPartitions = make(map[string][]int32, len(topics))
+		for _, t := range topics {
+			topicPartitions[t] = nil
+		}
+	}
+
+	status, err := c.admin.ListPartitionReassignments("", topicPartitions)
+	if err != nil {
+		c.record("list-partition-reassignments", err)
+		return nil, err
+	}
+
+	var out []PartitionReassignment
+	for topic, partitions := range status {
+		for partition, s := range partitions {
+			out = append(out, PartitionReassignment{
+				Topic:     topic,
+				Partition: partition,
+				Adding:    s.AddingReplicas,
+				Removing:  s.RemovingReplicas,
+			})
+		}
+	}
+	c.record("list-partition-reassignments", nil)
+	return out, nil
+}
+
+func (c *Client) record(op string, err error) {
+	if err != nil {
+		countAdminOps.WithLabelValues(op, outcomeError).Inc()
+		return
+	}
+	countAdminOps.WithLabelValues(op, outcomeSuccess).Inc()
+}
+
+func (c *Client) finish(sp opentracing.Span, op string, err error) error {
+	if err != nil {
+		trace.SpanError(sp)
+		countAdminOps.WithLabelValues(op, outcomeError).Inc()
+		return fmt.Errorf("failed to %s: %w", op, err)
+	}
+	trace.SpanSuccess(sp)
+	countAdminOps.WithLabelValues(op, outcomeSuccess).Inc()
+	return nil
+}
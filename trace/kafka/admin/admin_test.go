@@ -0,0 +1,43 @@
+package admin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHasGap(t *testing.T) {
+	assignment := map[int32][]int32{0: {1, 2, 3}, 10: {4, 5, 6}}
+	blocks := make([][]int32, 11)
+	blocks[0] = assignment[0]
+	blocks[10] = assignment[10]
+
+	assert.True(t, hasGap(blocks, assignment))
+
+	dense := map[int32][]int32{0: {1, 2, 3}, 1: {4, 5, 6}}
+	denseBlocks := make([][]int32, 2)
+	denseBlocks[0] = dense[0]
+	denseBlocks[1] = dense[1]
+
+	assert.False(t, hasGap(denseBlocks, dense))
+}
+
+func TestFillUnmentionedPartitions(t *testing.T) {
+	assignment := map[int32][]int32{0: {1, 2, 3}, 10: {4, 5, 6}}
+	blocks := make([][]int32, 11)
+	blocks[0] = assignment[0]
+	blocks[10] = assignment[10]
+
+	current := map[int32][]int32{}
+	for p := int32(1); p < 10; p++ {
+		current[p] = []int32{9, 8, 7}
+	}
+
+	fillUnmentionedPartitions(blocks, assignment, current)
+
+	assert.Equal(t, []int32{1, 2, 3}, blocks[0])
+	assert.Equal(t, []int32{4, 5, 6}, blocks[10])
+	for p := int32(1); p < 10; p++ {
+		assert.Equal(t, current[p], blocks[p], "partition %d should keep its current replicas, not be cancelled", p)
+	}
+}
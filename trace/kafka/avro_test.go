@@ -0,0 +1,117 @@
+package kafka
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeRegistry is an in-memory SchemaRegistry for tests, standing in for a
+// real Confluent Schema Registry HTTP round trip.
+type fakeRegistry struct {
+	nextID      int
+	idBySubject map[string]int
+	schemaByID  map[int]string
+}
+
+func newFakeRegistry() *fakeRegistry {
+	return &fakeRegistry{
+		idBySubject: make(map[string]int),
+		schemaByID:  make(map[int]string),
+	}
+}
+
+func (r *fakeRegistry) Register(subject, schema string) (int, error) {
+	key := subject + "\x00" + schema
+	if id, ok := r.idBySubject[key]; ok {
+		return id, nil
+	}
+	r.nextID++
+	r.idBySubject[key] = r.nextID
+	r.schemaByID[r.nextID] = schema
+	return r.nextID, nil
+}
+
+func (r *fakeRegistry) Schema(id int) (string, error) {
+	schema, ok := r.schemaByID[id]
+	if !ok {
+		return "", fmt.Errorf("no schema registered for id %d", id)
+	}
+	return schema, nil
+}
+
+const testUserSchema = `{"type":"record","name":"user","fields":[{"name":"name","type":"string"}]}`
+
+type testUser struct {
+	Name string `avro:"name"`
+}
+
+func TestAvroEncodeDecodeRoundTrip(t *testing.T) {
+	registry := newFakeRegistry()
+
+	enc, err := NewAvroEncoder(registry, "users-value", testUserSchema)
+	assert.NoError(t, err)
+
+	b, err := enc(testUser{Name: "ada"})
+	assert.NoError(t, err)
+	assert.Equal(t, byte(avroMagicByte), b[0])
+	assert.Len(t, b[avroFramingSize:], len(b)-avroFramingSize)
+
+	dec, err := NewAvroDecoder(registry)
+	assert.NoError(t, err)
+
+	var out testUser
+	assert.NoError(t, dec(b, &out))
+	assert.Equal(t, "ada", out.Name)
+}
+
+func TestAvroEncoderCachesSchemaID(t *testing.T) {
+	registry := newFakeRegistry()
+
+	enc1, err := NewAvroEncoder(registry, "users-value", testUserSchema)
+	assert.NoError(t, err)
+	enc2, err := NewAvroEncoder(registry, "users-value", testUserSchema)
+	assert.NoError(t, err)
+
+	b1, err := enc1(testUser{Name: "ada"})
+	assert.NoError(t, err)
+	b2, err := enc2(testUser{Name: "grace"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, b1[:avroFramingSize], b2[:avroFramingSize])
+	assert.Equal(t, 1, registry.nextID)
+}
+
+func TestAvroDecoderRejectsBadMagicByte(t *testing.T) {
+	registry := newFakeRegistry()
+	dec, err := NewAvroDecoder(registry)
+	assert.NoError(t, err)
+
+	var out testUser
+	assert.Error(t, dec([]byte{0x1, 0x0, 0x0, 0x0, 0x1}, &out))
+}
+
+func TestAvroDecoderRejectsShortPayload(t *testing.T) {
+	registry := newFakeRegistry()
+	dec, err := NewAvroDecoder(registry)
+	assert.NoError(t, err)
+
+	var out testUser
+	assert.Error(t, dec([]byte{0x0, 0x0}, &out))
+}
+
+func TestNewAvroEncoderRequiresRegistry(t *testing.T) {
+	_, err := NewAvroEncoder(nil, "subject", testUserSchema)
+	assert.Error(t, err)
+}
+
+func TestNewAvroEncoderRequiresSchema(t *testing.T) {
+	_, err := NewAvroEncoder(newFakeRegistry(), "subject", "")
+	assert.Error(t, err)
+}
+
+func TestTopicNameStrategy(t *testing.T) {
+	assert.Equal(t, "orders-value", TopicNameStrategy("orders", false))
+	assert.Equal(t, "orders-key", TopicNameStrategy("orders", true))
+}
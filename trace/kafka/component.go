@@ -35,6 +35,9 @@ type Builder struct {
 	tag         opentracing.Tag
 	enc         encoding.EncodeFunc
 	contentType string
+	retry       *retryPolicy
+	registry    SchemaRegistry
+	subjectFn   SubjectNameStrategy
 	errors      []error
 }
 
@@ -111,6 +114,38 @@ func (ab *Builder) WithEncoder(enc encoding.EncodeFunc, contentType string) *Bui
 	return ab
 }
 
+// WithSchemaRegistry configures ab with a Confluent Schema Registry client
+// and subject-naming strategy, used by AvroEncoder to register and frame
+// Avro-encoded messages. It leaves the Builder's default encoder alone;
+// apply the returned encoder per-message via Message.WithEncoder. strategy
+// defaults to TopicNameStrategy if nil.
+func (ab *Builder) WithSchemaRegistry(url string, strategy SubjectNameStrategy) *Builder {
+	if url == "" {
+		ab.errors = append(ab.errors, errors.New("schema registry url is empty"))
+		return ab
+	}
+	if strategy == nil {
+		strategy = TopicNameStrategy
+	}
+	log.Info(fieldSetMsg, "schema registry", url)
+	ab.registry = NewSchemaRegistryClient(url)
+	ab.subjectFn = strategy
+
+	return ab
+}
+
+// AvroEncoder returns an encoding.EncodeFunc that Avro-encodes a message
+// value for topic against schema, registering it (and caching the
+// subject -> schema ID lookup) on the Schema Registry configured via
+// WithSchemaRegistry.
+func (ab *Builder) AvroEncoder(topic, schema string, isKey bool) (encoding.EncodeFunc, error) {
+	if ab.registry == nil {
+		return nil, errors.New("schema registry is not configured, call WithSchemaRegistry first")
+	}
+	subject := ab.subjectFn(topic, isKey)
+	return NewAvroEncoder(ab.registry, subject, schema)
+}
+
 // WithBrokers sets the list of brokers the AsyncProducer will work with.
 func (ab *Builder) WithBrokers(brokers []string) *Builder {
 	if len(brokers) == 0 {
@@ -123,6 +158,56 @@ func (ab *Builder) WithBrokers(brokers []string) *Builder {
 	return ab
 }
 
+// WithRetries sets the maximum number of times to retry sending a message and the
+// backoff duration between retries, configuring sarama's Producer.Retry.Max/Backoff.
+func (ab *Builder) WithRetries(n int, backoff time.Duration) *Builder {
+	if n < 0 {
+		ab.errors = append(ab.errors, errors.New("retries must not be negative"))
+	}
+	if backoff <= 0*time.Second {
+		ab.errors = append(ab.errors, errors.New("retry backoff has to be positive"))
+	}
+	log.Info(fieldSetMsg, "retries", n)
+	ab.cfg.Producer.Retry.Max = n
+	ab.cfg.Producer.Retry.Backoff = backoff
+
+	return ab
+}
+
+// WithPartitioner sets the sarama partitioner used to assign messages that don't
+// pin a partition via Message.WithPartition. Use sarama.NewManualPartitioner
+// together with WithPartition for full manual control.
+func (ab *Builder) WithPartitioner(p sarama.PartitionerConstructor) *Builder {
+	if p == nil {
+		ab.errors = append(ab.errors, errors.New("partitioner is nil"))
+		return ab
+	}
+	log.Info(fieldSetMsg, "partitioner", p)
+	ab.cfg.Producer.Partitioner = p
+
+	return ab
+}
+
+// WithRetryPolicy enables Patron-layer retries for transient send errors on the
+// AsyncProducer, re-enqueuing the failed message up to max times with exponential
+// backoff (initial * 2^attempt, capped at maxBackoff, jittered by ± jitter) before
+// the error is pushed to the error channel.
+func (ab *Builder) WithRetryPolicy(max int, initial, maxBackoff time.Duration, jitter float64) *Builder {
+	if max < 0 {
+		ab.errors = append(ab.errors, errors.New("max retries must not be negative"))
+	}
+	if initial <= 0*time.Second || maxBackoff <= 0*time.Second {
+		ab.errors = append(ab.errors, errors.New("retry backoff durations must be positive"))
+	}
+	if jitter < 0 || jitter > 1 {
+		ab.errors = append(ab.errors, errors.New("jitter must be between 0 and 1"))
+	}
+	log.Info(fieldSetMsg, "retry policy", max)
+	ab.retry = newRetryPolicy(max, initial, maxBackoff, jitter)
+
+	return ab
+}
+
 // Create constructs the AsyncProducer component by applying the gathered properties.
 func (ab *Builder) Create() (*AsyncProducer, error) {
 	if len(ab.errors) > 0 {
@@ -141,8 +226,34 @@ func (ab *Builder) Create() (*AsyncProducer, error) {
 		enc:         ab.enc,
 		contentType: ab.contentType,
 		tag:         ab.tag,
+		retry:       ab.retry,
+		closing:     make(chan struct{}),
 	}
 
 	go ap.propagateError()
 	return &ap, nil
 }
+
+// CreateSync constructs a SyncProducer by applying the gathered properties.
+// Producer.Return.Successes is forced to true, since sarama.NewSyncProducer
+// requires it in order to hand back the partition/offset of a successful send.
+func (ab *Builder) CreateSync() (*SyncProducer, error) {
+	if len(ab.errors) > 0 {
+		return nil, errors.Aggregate(ab.errors...)
+	}
+
+	ab.cfg.Producer.Return.Successes = true
+
+	prod, err := sarama.NewSyncProducer(ab.brokers, ab.cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create sync producer")
+	}
+
+	return &SyncProducer{
+		cfg:         ab.cfg,
+		prod:        prod,
+		enc:         ab.enc,
+		contentType: ab.contentType,
+		tag:         opentracing.Tag{Key: "type", Value: "sync"},
+	}, nil
+}
@@ -122,3 +122,60 @@ func TestEncoder(t *testing.T) {
 		})
 	}
 }
+
+func TestWithSchemaRegistry(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		wantErr bool
+	}{
+		{name: "success", url: "http://localhost:8081", wantErr: false},
+		{name: "fail, empty url", url: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ab := NewBuilder().WithSchemaRegistry(tt.url, nil)
+			if tt.wantErr {
+				assert.NotEmpty(t, ab.errors)
+			} else {
+				assert.Empty(t, ab.errors)
+				assert.NotNil(t, ab.registry)
+				assert.NotNil(t, ab.subjectFn)
+			}
+		})
+	}
+}
+
+func TestBuilder_AvroEncoder_RequiresSchemaRegistry(t *testing.T) {
+	ab := NewBuilder()
+	_, err := ab.AvroEncoder("orders", `{"type":"string"}`, false)
+	assert.Error(t, err)
+}
+
+func TestRetries(t *testing.T) {
+	type args struct {
+		n       int
+		backoff time.Duration
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{name: "success", args: args{n: 5, backoff: time.Second}, wantErr: false},
+		{name: "fail, negative retries", args: args{n: -1, backoff: time.Second}, wantErr: true},
+		{name: "fail, zero backoff", args: args{n: 5, backoff: 0}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ab := NewBuilder().WithRetries(tt.args.n, tt.args.backoff)
+			if tt.wantErr {
+				assert.NotEmpty(t, ab.errors)
+			} else {
+				assert.Empty(t, ab.errors)
+				assert.Equal(t, tt.args.n, ab.cfg.Producer.Retry.Max)
+				assert.Equal(t, tt.args.backoff, ab.cfg.Producer.Retry.Backoff)
+			}
+		})
+	}
+}
@@ -0,0 +1,625 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/beatlabs/patron/async"
+	"github.com/beatlabs/patron/correlation"
+	"github.com/beatlabs/patron/encoding"
+	"github.com/beatlabs/patron/errors"
+	"github.com/beatlabs/patron/log"
+	"github.com/beatlabs/patron/trace"
+	"github.com/google/uuid"
+	"github.com/opentracing/opentracing-go"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const consumerGroupComponent = "kafka-consumer-group"
+
+// FailStrategy dictates what a Component does when batch processing fails.
+type FailStrategy int
+
+const (
+	// ExitOnError terminates the component's Run loop, surfacing the error.
+	ExitOnError FailStrategy = iota
+	// SkipOnError logs the failure and continues consuming, skipping the batch.
+	SkipOnError
+)
+
+var (
+	countMessagesConsumed *prometheus.CounterVec
+	processingLatency     *prometheus.HistogramVec
+	countRebalances       *prometheus.CounterVec
+)
+
+func init() {
+	countMessagesConsumed = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "component",
+			Subsystem: "kafka_consumer_group",
+			Name:      "messages_consumed",
+			Help:      "Messages consumed counter, classified by group and topic",
+		}, []string{"group", "topic"},
+	)
+	processingLatency = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "component",
+			Subsystem: "kafka_consumer_group",
+			Name:      "processing_latency_seconds",
+			Help:      "Batch processing latency in seconds, classified by group and topic",
+		}, []string{"group", "topic"},
+	)
+	countRebalances = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "component",
+			Subsystem: "kafka_consumer_group",
+			Name:      "rebalances",
+			Help:      "Consumer group rebalance events counter, classified by group",
+		}, []string{"group"},
+	)
+	prometheus.MustRegister(countMessagesConsumed, processingLatency, countRebalances)
+}
+
+// message wraps a claimed sarama.ConsumerMessage together with its decoder and tracing span.
+type message struct {
+	ctx  context.Context
+	span opentracing.Span
+	msg  *sarama.ConsumerMessage
+	sess sarama.ConsumerGroupSession
+	dec  encoding.DecodeRawFunc
+}
+
+// Context returns the context carrying the message's tracing span and correlation id.
+func (m *message) Context() context.Context {
+	return m.ctx
+}
+
+// Decode transforms the message's raw value into a business entity.
+func (m *message) Decode(v interface{}) error {
+	return m.dec(m.msg.Value, v)
+}
+
+// Source returns the kafka topic where the message arrived.
+func (m *message) Source() string {
+	return m.msg.Topic
+}
+
+// Batch is a group of claimed messages, decoded and ready for processing.
+type Batch []*message
+
+// ProcessBatchFunc processes a batch of messages, returning an error fails it as a whole.
+type ProcessBatchFunc func(ctx context.Context, b Batch) error
+
+// ConsumerBuilder gathers the required and optional properties in order to construct
+// a Kafka consumer-group Component, mirroring the producer's Builder.
+type ConsumerBuilder struct {
+	brokers        []string
+	group          string
+	topics         []string
+	cfg            *sarama.Config
+	batchSize      int
+	batchTimeout   time.Duration
+	failStrategy   FailStrategy
+	retries        int
+	retryBackoff   time.Duration
+	backoffMax     time.Duration
+	backoffFactor  float64
+	commitSync     bool
+	copartitioned  bool
+	strategy       sarama.BalanceStrategy
+	offsetsChecker OffsetsChecker
+	registry       SchemaRegistry
+	errors         []error
+}
+
+// NewConsumerBuilder initiates the consumer-group Component builder chain.
+func NewConsumerBuilder(group string, topics []string) *ConsumerBuilder {
+	var errs []error
+	if group == "" {
+		errs = append(errs, errors.New("group is required"))
+	}
+	if len(topics) == 0 {
+		errs = append(errs, errors.New("topics are required"))
+	}
+	cfg := sarama.NewConfig()
+	cfg.Version = sarama.V0_11_0_0
+	cfg.Consumer.Return.Errors = true
+	return &ConsumerBuilder{
+		group:          group,
+		topics:         topics,
+		cfg:            cfg,
+		batchSize:      1,
+		batchTimeout:   0,
+		failStrategy:   ExitOnError,
+		backoffFactor:  2,
+		commitSync:     true,
+		offsetsChecker: noopOffsetsChecker{},
+		errors:         errs,
+	}
+}
+
+// WithBrokers sets the list of brokers the consumer group will work with.
+func (cb *ConsumerBuilder) WithBrokers(brokers []string) *ConsumerBuilder {
+	if len(brokers) == 0 {
+		cb.errors = append(cb.errors, errors.New("brokers list is empty"))
+	} else {
+		log.Info(fieldSetMsg, "brokers", brokers)
+		cb.brokers = append(cb.brokers, brokers...)
+	}
+	return cb
+}
+
+// WithGroup overrides the consumer group id.
+func (cb *ConsumerBuilder) WithGroup(group string) *ConsumerBuilder {
+	if group == "" {
+		cb.errors = append(cb.errors, errors.New("group is required"))
+		return cb
+	}
+	cb.group = group
+	return cb
+}
+
+// WithTopics overrides the topics the consumer group subscribes to.
+func (cb *ConsumerBuilder) WithTopics(topics []string) *ConsumerBuilder {
+	if len(topics) == 0 {
+		cb.errors = append(cb.errors, errors.New("topics are required"))
+		return cb
+	}
+	cb.topics = topics
+	return cb
+}
+
+// WithVersion sets the kafka version for the consumer group.
+func (cb *ConsumerBuilder) WithVersion(version string) *ConsumerBuilder {
+	if version == "" {
+		cb.errors = append(cb.errors, errors.New("version is required"))
+		return cb
+	}
+	v, err := sarama.ParseKafkaVersion(version)
+	if err != nil {
+		cb.errors = append(cb.errors, errors.New("failed to parse kafka version"))
+		return cb
+	}
+	log.Info(fieldSetMsg, "version", version)
+	cb.cfg.Version = v
+	return cb
+}
+
+// WithSaramaConfig overrides the sarama config used by the consumer group wholesale.
+func (cb *ConsumerBuilder) WithSaramaConfig(cfg *sarama.Config) *ConsumerBuilder {
+	if cfg == nil {
+		cb.errors = append(cb.errors, errors.New("sarama config is nil"))
+		return cb
+	}
+	cb.cfg = cfg
+	return cb
+}
+
+// WithBatch sets the batch size and the max duration the component waits to fill it
+// before dispatching a partial batch to Process.
+func (cb *ConsumerBuilder) WithBatch(size int, timeout time.Duration) *ConsumerBuilder {
+	if size <= 0 {
+		cb.errors = append(cb.errors, errors.New("batch size must be positive"))
+	}
+	cb.batchSize = size
+	cb.batchTimeout = timeout
+	return cb
+}
+
+// WithFailStrategy sets what happens when Process returns an error.
+func (cb *ConsumerBuilder) WithFailStrategy(fs FailStrategy) *ConsumerBuilder {
+	cb.failStrategy = fs
+	return cb
+}
+
+// WithRetries sets the number of autoreconnect attempts and the initial backoff
+// duration used when the consumer group session fails.
+func (cb *ConsumerBuilder) WithRetries(n int, backoff time.Duration) *ConsumerBuilder {
+	if n < 0 {
+		cb.errors = append(cb.errors, errors.New("retries must not be negative"))
+	}
+	if backoff <= 0*time.Second {
+		cb.errors = append(cb.errors, errors.New("retry backoff has to be positive"))
+	}
+	cb.retries = n
+	cb.retryBackoff = backoff
+	return cb
+}
+
+// WithReconnectBackoff configures the exponential backoff Run uses between
+// consumer group session failures: it starts at the min backoff set via
+// WithRetries, grows by factor on every further attempt, and is capped at
+// max. The default factor is 2, with no cap.
+func (cb *ConsumerBuilder) WithReconnectBackoff(max time.Duration, factor float64) *ConsumerBuilder {
+	if max <= 0 {
+		cb.errors = append(cb.errors, errors.New("max reconnect backoff must be positive"))
+	}
+	if factor <= 1 {
+		cb.errors = append(cb.errors, errors.New("reconnect backoff factor must be greater than 1"))
+	}
+	cb.backoffMax = max
+	cb.backoffFactor = factor
+	return cb
+}
+
+// WithCommitSync toggles synchronous offset commits after a batch is processed
+// successfully; when false, sarama's auto-commit handles offsets on its own schedule.
+func (cb *ConsumerBuilder) WithCommitSync(sync bool) *ConsumerBuilder {
+	cb.commitSync = sync
+	return cb
+}
+
+// WithCopartitionedRebalance assigns the same partition number of every subscribed
+// topic to the same consumer group member, so that joined topics stay aligned.
+// It is a shorthand for WithRebalanceStrategy(CoPartitioningStrategy()).
+func (cb *ConsumerBuilder) WithCopartitionedRebalance() *ConsumerBuilder {
+	cb.copartitioned = true
+	return cb
+}
+
+// WithRebalanceStrategy overrides the consumer group's partition assignment
+// strategy, e.g. sarama.BalanceStrategySticky or CoPartitioningStrategy().
+// It takes precedence over WithCopartitionedRebalance.
+func (cb *ConsumerBuilder) WithRebalanceStrategy(strategy sarama.BalanceStrategy) *ConsumerBuilder {
+	if strategy == nil {
+		cb.errors = append(cb.errors, errors.New("rebalance strategy is nil"))
+		return cb
+	}
+	cb.strategy = strategy
+	return cb
+}
+
+// WithOffsetsChecker configures cb with an OffsetsChecker that Component.Run
+// calls before it ever starts consuming, gating message dispatch until it
+// succeeds. It defaults to a no-op; pass a ConsumerGroupOffsetsChecker to
+// guarantee committed offsets exist for every partition before the group
+// joins, closing the race where messages produced right after the group's
+// first subscription are lost.
+func (cb *ConsumerBuilder) WithOffsetsChecker(checker OffsetsChecker) *ConsumerBuilder {
+	if checker == nil {
+		cb.errors = append(cb.errors, errors.New("offsets checker is nil"))
+		return cb
+	}
+	cb.offsetsChecker = checker
+	return cb
+}
+
+// WithSchemaRegistry configures cb with a Confluent Schema Registry client,
+// so messages produced with the AvroContentType header are decoded through
+// NewAvroDecoder instead of the content-type-registered decoder.
+func (cb *ConsumerBuilder) WithSchemaRegistry(url string) *ConsumerBuilder {
+	if url == "" {
+		cb.errors = append(cb.errors, errors.New("schema registry url is empty"))
+		return cb
+	}
+	log.Info(fieldSetMsg, "schema registry", url)
+	cb.registry = NewSchemaRegistryClient(url)
+	return cb
+}
+
+// Create constructs the consumer-group Component by applying the gathered properties.
+func (cb *ConsumerBuilder) Create(proc ProcessBatchFunc) (*Component, error) {
+	if proc == nil {
+		cb.errors = append(cb.errors, errors.New("processor function is required"))
+	}
+	if len(cb.errors) > 0 {
+		return nil, errors.Aggregate(cb.errors...)
+	}
+
+	if cb.copartitioned && cb.strategy == nil {
+		cb.strategy = CoPartitioningStrategy()
+	}
+	if cb.strategy != nil {
+		cb.cfg.Consumer.Group.Rebalance.Strategy = cb.strategy
+	}
+
+	return &Component{
+		brokers:        cb.brokers,
+		group:          cb.group,
+		topics:         cb.topics,
+		cfg:            cb.cfg,
+		batchSize:      cb.batchSize,
+		batchTimeout:   cb.batchTimeout,
+		failStrategy:   cb.failStrategy,
+		retries:        cb.retries,
+		retryBackoff:   cb.retryBackoff,
+		backoffMax:     cb.backoffMax,
+		backoffFactor:  cb.backoffFactor,
+		commitSync:     cb.commitSync,
+		proc:           proc,
+		registry:       cb.registry,
+		strategyName:   cb.cfg.Consumer.Group.Rebalance.Strategy.Name(),
+		offsetsChecker: cb.offsetsChecker,
+		state:          newStateTracker(cb.group),
+		failCh:         make(chan error, 1),
+	}, nil
+}
+
+// Component implements a Kafka consumer group that can be registered with
+// patron.WithComponents.
+type Component struct {
+	brokers        []string
+	group          string
+	topics         []string
+	cfg            *sarama.Config
+	batchSize      int
+	batchTimeout   time.Duration
+	failStrategy   FailStrategy
+	retries        int
+	retryBackoff   time.Duration
+	backoffMax     time.Duration
+	backoffFactor  float64
+	commitSync     bool
+	proc           ProcessBatchFunc
+	registry       SchemaRegistry
+	strategyName   string
+	offsetsChecker OffsetsChecker
+	state          *stateTracker
+	failCh         chan error
+}
+
+// State returns the Component's current connection lifecycle state.
+func (c *Component) State() State {
+	return c.state.get()
+}
+
+// Observe registers ch to receive every subsequent State transition. Sends
+// are non-blocking, so a slow receiver misses transitions rather than
+// stalling Run.
+func (c *Component) Observe(ch chan State) {
+	c.state.observe(ch)
+}
+
+// Run starts consuming, re-creating the sarama.ConsumerGroup with capped exponential
+// backoff whenever the session terminates with an error, until ctx is cancelled.
+// Before the first attempt, it blocks on offsetsChecker.Check so a
+// ConsumerGroupOffsetsChecker can seed missing offsets before the group
+// ever joins.
+func (c *Component) Run(ctx context.Context) error {
+	c.state.set(Connecting)
+	if err := c.offsetsChecker.Check(ctx, c.cfg, c.brokers, c.group, c.topics); err != nil {
+		c.state.set(Stopped)
+		return fmt.Errorf("failed to check consumer group offsets: %w", err)
+	}
+
+	attempt := 0
+	for {
+		cg, err := sarama.NewConsumerGroup(c.brokers, c.group, c.cfg)
+		if err != nil {
+			c.state.set(Stopped)
+			return fmt.Errorf("failed to create consumer group: %w", err)
+		}
+
+		h := &groupHandler{component: c}
+		c.state.set(Connected)
+		runErr := cg.Consume(ctx, c.topics, h)
+		_ = cg.Close()
+
+		if ctx.Err() != nil {
+			c.state.set(Stopped)
+			return nil
+		}
+		if runErr == nil {
+			attempt = 0
+			c.state.set(Connecting)
+			continue
+		}
+
+		attempt++
+		if c.retries > 0 && attempt > c.retries {
+			c.state.set(Stopped)
+			return fmt.Errorf("consumer group exhausted %d retries: %w", c.retries, runErr)
+		}
+		backoff := c.nextBackoff(attempt)
+		log.Errorf("consumer group session failed, reconnecting in %s: %v", backoff, runErr)
+		c.state.set(Reconnecting)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			c.state.set(Stopped)
+			return nil
+		}
+	}
+}
+
+// nextBackoff computes the delay before reconnect attempt, growing
+// retryBackoff by backoffFactor on every further attempt and capping the
+// result at backoffMax when it is set.
+func (c *Component) nextBackoff(attempt int) time.Duration {
+	factor := c.backoffFactor
+	if factor <= 1 {
+		factor = 2
+	}
+	backoff := time.Duration(float64(c.retryBackoff) * math.Pow(factor, float64(attempt-1)))
+	if c.backoffMax > 0 && backoff > c.backoffMax {
+		return c.backoffMax
+	}
+	return backoff
+}
+
+type groupHandler struct {
+	component *Component
+}
+
+// Setup is run at the beginning of a new session, before ConsumeClaim.
+func (h *groupHandler) Setup(sarama.ConsumerGroupSession) error {
+	countRebalances.WithLabelValues(h.component.group).Inc()
+	return nil
+}
+
+// Cleanup is run at the end of a session, once all ConsumeClaim goroutines have exited.
+func (h *groupHandler) Cleanup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+// ConsumeClaim decodes claimed messages in batches and dispatches them to Process.
+func (h *groupHandler) ConsumeClaim(sess sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	c := h.component
+	batch := make(Batch, 0, c.batchSize)
+
+	var flushTimer <-chan time.Time
+	var t *time.Timer
+	if c.batchTimeout > 0 {
+		t = time.NewTimer(c.batchTimeout)
+		defer t.Stop()
+		flushTimer = t.C
+	}
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		start := time.Now()
+		err := c.proc(context.Background(), batch)
+		processingLatency.WithLabelValues(c.group, claim.Topic()).Observe(time.Since(start).Seconds())
+		if err != nil {
+			for _, m := range batch {
+				trace.SpanError(m.span)
+			}
+			if c.failStrategy == ExitOnError {
+				batch = batch[:0]
+				return err
+			}
+			log.Errorf("failed to process batch, skipping: %v", err)
+			batch = batch[:0]
+			return nil
+		}
+
+		for _, m := range batch {
+			trace.SpanSuccess(m.span)
+		}
+		last := batch[len(batch)-1]
+		sess.MarkMessage(last.msg, "")
+		if c.commitSync {
+			sess.Commit()
+		}
+		batch = batch[:0]
+		return nil
+	}
+
+	for {
+		select {
+		case msg, ok := <-claim.Messages():
+			if !ok {
+				return flush()
+			}
+			countMessagesConsumed.WithLabelValues(c.group, msg.Topic).Inc()
+			m, err := h.claimMessage(sess, msg)
+			if err != nil {
+				log.Errorf("failed to decode message: %v", err)
+				continue
+			}
+			batch = append(batch, m)
+			if len(batch) >= c.batchSize {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+		case <-flushTimer:
+			if err := flush(); err != nil {
+				return err
+			}
+			t.Reset(c.batchTimeout)
+		case <-sess.Context().Done():
+			return flush()
+		}
+	}
+}
+
+func (h *groupHandler) claimMessage(sess sarama.ConsumerGroupSession, msg *sarama.ConsumerMessage) (*message, error) {
+	corID := getCorrelationID(msg.Headers)
+
+	sp, ctx := trace.ConsumerSpan(context.Background(), trace.ComponentOpName(consumerGroupComponent, msg.Topic),
+		consumerGroupComponent, corID, mapHeader(msg.Headers))
+	ctx = correlation.ContextWithID(ctx, corID)
+	sp.SetTag("rebalance.strategy", h.component.strategyName)
+
+	if cp, ok := parsePipelineCheckpoint(msg.Headers); ok {
+		pipelineLatency.WithLabelValues(cp.service, msg.Topic, h.component.group).Observe(time.Since(cp.producedAt).Seconds())
+	}
+	consumerLag.WithLabelValues(h.component.group, msg.Topic).Set(time.Since(msg.Timestamp).Seconds())
+
+	ct, err := determineContentType(msg.Headers)
+	if err != nil {
+		if h.component.registry == nil {
+			trace.SpanError(sp)
+			return nil, fmt.Errorf("failed to determine content type from message headers: %w", err)
+		}
+		if _, ok := confluentFrame(msg.Value); !ok {
+			trace.SpanError(sp)
+			return nil, fmt.Errorf("failed to determine content type from message headers: %w", err)
+		}
+		// No content-type header, but the payload carries Confluent's wire
+		// framing - likely produced by Kafka Connect or ksqlDB rather than
+		// patron itself. Default to Avro, the framing's original format.
+		ct = AvroContentType
+	}
+
+	if id, ok := confluentFrame(msg.Value); ok {
+		sp.SetTag("schema.id", id)
+		sp.SetTag("schema.subject", TopicNameStrategy(msg.Topic, false))
+	}
+
+	var dec encoding.DecodeRawFunc
+	switch {
+	case ct == AvroContentType:
+		if h.component.registry == nil {
+			trace.SpanError(sp)
+			return nil, errors.New("message has avro content type but no schema registry is configured")
+		}
+		dec, err = NewAvroDecoder(h.component.registry)
+	case ct == ProtobufContentType:
+		if h.component.registry == nil {
+			trace.SpanError(sp)
+			return nil, errors.New("message has protobuf content type but no schema registry is configured")
+		}
+		dec, err = NewProtobufDecoder(h.component.registry)
+	default:
+		dec, err = async.DetermineDecoder(ct)
+	}
+	if err != nil {
+		trace.SpanError(sp)
+		return nil, fmt.Errorf("failed to determine decoder from content type %s: %w", ct, err)
+	}
+
+	return &message{
+		ctx:  ctx,
+		dec:  dec,
+		span: sp,
+		msg:  msg,
+		sess: sess,
+	}, nil
+}
+
+func getCorrelationID(hh []*sarama.RecordHeader) string {
+	for _, h := range hh {
+		if string(h.Key) == correlation.HeaderID {
+			if len(h.Value) > 0 {
+				return string(h.Value)
+			}
+			break
+		}
+	}
+	return uuid.New().String()
+}
+
+func determineContentType(hdr []*sarama.RecordHeader) (string, error) {
+	for _, h := range hdr {
+		if string(h.Key) == encoding.ContentTypeHeader {
+			return string(h.Value), nil
+		}
+	}
+	return "", errors.New("content type header is missing")
+}
+
+func mapHeader(hh []*sarama.RecordHeader) map[string]string {
+	mp := make(map[string]string)
+	for _, h := range hh {
+		mp[string(h.Key)] = string(h.Value)
+	}
+	return mp
+}
@@ -0,0 +1,110 @@
+package kafka
+
+import (
+	"fmt"
+	"hash/fnv"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// pipelineCheckpointHeader carries a Data Streams Monitoring-style pipeline
+// checkpoint on every produced message, letting a downstream consumer
+// compute end-to-end per-hop latency without the producer and consumer
+// sharing a clock any more precisely than a single wall-clock timestamp.
+const pipelineCheckpointHeader = "pt-dsm"
+
+// serviceName identifies this process as a pipeline checkpoint's origin,
+// overridable via SetServiceName. It defaults to the process hostname.
+var serviceName = defaultServiceName()
+
+func defaultServiceName() string {
+	if h, err := os.Hostname(); err == nil {
+		return h
+	}
+	return "unknown"
+}
+
+// SetServiceName overrides the service name trace/kafka stamps on outgoing
+// pipeline checkpoints; incoming checkpoints are reported under the service
+// their header carries. Call it once
+// during startup, e.g. alongside patron.Setup.
+func SetServiceName(name string) {
+	serviceName = name
+}
+
+// checkpointHash fingerprints a (service, topic, direction) pipeline edge,
+// the way Datadog's data-streams-go does, so a chain of hops can be
+// reconstructed from a stream of checkpoints without recomputing it from
+// the plaintext service/topic on every hop.
+func checkpointHash(service, topic, direction string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(service + "|" + topic + "|" + direction))
+	return h.Sum64()
+}
+
+// stampPipelineCheckpoint attaches a pipeline checkpoint header to msg,
+// recording service as topic's produce-time origin.
+func stampPipelineCheckpoint(msg *sarama.ProducerMessage, service, topic string) {
+	hash := checkpointHash(service, topic, "out")
+	value := fmt.Sprintf("%d|%s|%x", time.Now().UnixNano(), service, hash)
+	msg.Headers = append(msg.Headers, sarama.RecordHeader{
+		Key:   []byte(pipelineCheckpointHeader),
+		Value: []byte(value),
+	})
+}
+
+// pipelineCheckpoint is a parsed pt-dsm header.
+type pipelineCheckpoint struct {
+	producedAt time.Time
+	service    string
+}
+
+// parsePipelineCheckpoint extracts the pipeline checkpoint stamped by
+// stampPipelineCheckpoint from a consumed message's headers, if present.
+func parsePipelineCheckpoint(hh []*sarama.RecordHeader) (pipelineCheckpoint, bool) {
+	for _, h := range hh {
+		if string(h.Key) != pipelineCheckpointHeader {
+			continue
+		}
+		parts := strings.SplitN(string(h.Value), "|", 3)
+		if len(parts) != 3 {
+			return pipelineCheckpoint{}, false
+		}
+		nanos, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil {
+			return pipelineCheckpoint{}, false
+		}
+		return pipelineCheckpoint{producedAt: time.Unix(0, nanos), service: parts[1]}, true
+	}
+	return pipelineCheckpoint{}, false
+}
+
+var (
+	pipelineLatency *prometheus.HistogramVec
+	consumerLag     *prometheus.GaugeVec
+)
+
+func init() {
+	pipelineLatency = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "component",
+			Subsystem: "kafka",
+			Name:      "pipeline_latency_seconds",
+			Help:      "End-to-end latency between a pipeline checkpoint's produce time and its consumption here, classified by upstream service, topic and consumer group",
+		}, []string{"from_service", "topic", "group"},
+	)
+	consumerLag = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "component",
+			Subsystem: "kafka",
+			Name:      "consumer_lag_seconds",
+			Help:      "Seconds between a claimed message's broker timestamp and now, classified by group and topic",
+		}, []string{"group", "topic"},
+	)
+	prometheus.MustRegister(pipelineLatency, consumerLag)
+}
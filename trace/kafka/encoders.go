@@ -1,72 +1,30 @@
 package kafka
 
-import (
-	// "github.com/beatlabs/patron/encoding"
-	"github.com/beatlabs/patron/encoding/json"
-	"github.com/beatlabs/patron/encoding/protobuf"
-)
-
-// JSONEncoder implements the Encoder interface for JSON Objects so that they can be used
-// as the Key or Value in a ProducerMessage.
-type JSONEncoder []byte
-
-// ProtobufEncoder implements the Encoder interface for Protocol Buffers Objects so
-// that they can be used as the Key or Value in a ProducerMessage.
-type ProtobufEncoder []byte
-
-// Encode satisfies the Encode() function of the encoder interface
-func (j JSONEncoder) Encode() ([]byte, error) {
-	b, err := json.Encode(j)
-	if err != nil {
-		return []byte{}, err
-	}
-	return b, nil
+import "github.com/beatlabs/patron/encoding"
+
+// MessageEncoder serializes a message's body to bytes. It replaces the
+// former JSONEncoder/ProtobufEncoder []byte aliases, which only ever
+// worked for a payload that already was []byte; pairing an arbitrary body
+// value with the encoding.EncodeFunc that serializes it, at message
+// construction time, lifts that restriction.
+type MessageEncoder interface {
+	Encode() ([]byte, error)
 }
 
-// Length satisfies the Length() function of the encoder interface
-func (j JSONEncoder) Length() int {
-	return len(j)
+// messageEncoder adapts a body value and the encoding.EncodeFunc that
+// serializes it into a MessageEncoder.
+type messageEncoder struct {
+	body interface{}
+	enc  encoding.EncodeFunc
 }
 
-// Encode satisfies the Encode() function of the encoder interface
-func (p ProtobufEncoder) Encode() ([]byte, error) {
-	b, err := protobuf.Encode(p)
-	if err != nil {
-		return []byte{}, err
-	}
-	return b, nil
+// Encode implements MessageEncoder.
+func (e messageEncoder) Encode() ([]byte, error) {
+	return e.enc(e.body)
 }
 
-// Length satisfies the Length() function of the encoder interface
-func (p ProtobufEncoder) Length() int {
-	return len(p)
+// NewMessageEncoder pairs body with enc, the encoding.EncodeFunc that
+// serializes it. See Message.WithEncoder.
+func NewMessageEncoder(body interface{}, enc encoding.EncodeFunc) MessageEncoder {
+	return messageEncoder{body: body, enc: enc}
 }
-
-// ***********************************
-// I also tried this, to dynamically set the method but it didn't work out
-// ***********************************
-// // EncoderObj does something
-// type EncoderObj []byte
-
-// // EncoderImpl implements the dynamic method setting for our encoder
-// type EncoderImpl struct {
-// 	EncoderObj EncoderObj
-// 	Encode     func() ([]byte, error)
-// 	Length     func() int
-// }
-
-// // SetEncoder sets the encoder
-// func (e *EncoderImpl) SetEncoder(enc encoding.EncodeFunc) EncoderImpl {
-// 	return EncoderImpl{
-// 		Encode: func() ([]byte, error) {
-// 			m, err := enc(e.EncoderObj)
-// 			if err != nil {
-// 				return []byte{}, err
-// 			}
-// 			return m, nil
-// 		},
-// 		Length: func() int {
-// 			return len(e.EncoderObj)
-// 		},
-// 	}
-// }
@@ -0,0 +1,36 @@
+package kafka
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessageEncoder(t *testing.T) {
+	enc := NewMessageEncoder("body", func(v interface{}) ([]byte, error) {
+		return []byte(v.(string)), nil
+	})
+	b, err := enc.Encode()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("body"), b)
+}
+
+func TestMessageEncoderPropagatesError(t *testing.T) {
+	wantErr := errors.New("boom")
+	enc := NewMessageEncoder("body", func(interface{}) ([]byte, error) {
+		return nil, wantErr
+	})
+	_, err := enc.Encode()
+	assert.Equal(t, wantErr, err)
+}
+
+func TestMessage_WithEncoder(t *testing.T) {
+	m := NewMessage("topic", 42).WithEncoder(func(v interface{}) ([]byte, error) {
+		return []byte("custom"), nil
+	})
+
+	b, err := m.encoder.Encode()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("custom"), b)
+}
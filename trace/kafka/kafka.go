@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/Shopify/sarama"
 	"github.com/beatlabs/patron/correlation"
@@ -16,6 +18,9 @@ import (
 
 const (
 	producerComponent = "kafka-async-producer"
+
+	producerTypeAsync = "async"
+	producerTypeSync  = "sync"
 )
 
 var countMessagesSent *prometheus.CounterVec
@@ -23,13 +28,13 @@ var countMessageSendErrors *prometheus.CounterVec
 var countMessageCreationErrors *prometheus.CounterVec
 
 // CountMessagesSentInc increments the countMessagesSent counter.
-func CountMessagesSentInc(topic string) {
-	countMessagesSent.WithLabelValues(topic).Inc()
+func CountMessagesSentInc(topic, producerType string) {
+	countMessagesSent.WithLabelValues(topic, producerType).Inc()
 }
 
 // CountMessageSendErrorsInc increments the countMessageSendErrors counter.
-func CountMessageSendErrorsInc(topic string) {
-	countMessageSendErrors.WithLabelValues(topic).Inc()
+func CountMessageSendErrorsInc(topic, producerType string) {
+	countMessageSendErrors.WithLabelValues(topic, producerType).Inc()
 }
 
 // CountMessageCreationErrorsInc increments the countMessageCreationErrors counter.
@@ -44,7 +49,7 @@ func init() {
 			Subsystem: "kafka_async_producer",
 			Name:      "messages_sent",
 			Help:      "Messages sent counter, classified by topic",
-		}, []string{"topic"},
+		}, []string{"topic", "producer_type"},
 	)
 	countMessageSendErrors = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
@@ -52,7 +57,7 @@ func init() {
 			Subsystem: "kafka_async_producer",
 			Name:      "message_send_errors",
 			Help:      "Message send errors counter, classified by topic",
-		}, []string{"topic"},
+		}, []string{"topic", "producer_type"},
 	)
 	countMessageCreationErrors = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
@@ -71,9 +76,13 @@ func init() {
 
 // Message abstraction of a Kafka message.
 type Message struct {
-	topic string
-	body  interface{}
-	key   *string
+	topic     string
+	body      interface{}
+	encoder   MessageEncoder
+	key       *string
+	keyBytes  []byte
+	partition *int32
+	headers   map[string]string
 }
 
 // NewMessage creates a new message.
@@ -89,6 +98,52 @@ func NewMessageWithKey(t string, b interface{}, k string) (*Message, error) {
 	return &Message{topic: t, body: b, key: &k}, nil
 }
 
+// NewMessageWithKeyBytes creates a new message with an associated binary key, for
+// pipelines (e.g. schema-registry-backed ones) whose partitioning key isn't a string.
+func NewMessageWithKeyBytes(t string, b interface{}, k []byte) (*Message, error) {
+	if len(k) == 0 {
+		return nil, errors.New("key bytes can not be empty")
+	}
+	return &Message{topic: t, body: b, keyBytes: k}, nil
+}
+
+// WithEncoder overrides the producer's configured encoder for this message
+// only, so a single producer can mix payload encodings across calls - for
+// example, most messages going through the Builder's default JSON encoder
+// while a few are Avro-encoded against a schema registry via
+// NewAvroEncoder.
+func (m *Message) WithEncoder(enc encoding.EncodeFunc) *Message {
+	m.encoder = NewMessageEncoder(m.body, enc)
+	return m
+}
+
+// WithHeader attaches an application header that survives into the
+// sarama.ProducerMessage headers, as long as it doesn't collide with the
+// correlation/content-type headers Patron reserves for itself (see
+// reservedHeaders); the tracer's injected headers are not checked.
+func (m *Message) WithHeader(k, v string) *Message {
+	if m.headers == nil {
+		m.headers = make(map[string]string)
+	}
+	m.headers[k] = v
+	return m
+}
+
+// WithHeaders attaches a set of application headers, see WithHeader.
+func (m *Message) WithHeaders(hh map[string]string) *Message {
+	for k, v := range hh {
+		m.WithHeader(k, v)
+	}
+	return m
+}
+
+// WithPartition pins the message to a specific partition, bypassing the producer's
+// partitioner.
+func (m *Message) WithPartition(p int32) *Message {
+	m.partition = &p
+	return m
+}
+
 // Producer interface for Kafka.
 type Producer interface {
 	Send(ctx context.Context, msg *Message) error
@@ -96,6 +151,11 @@ type Producer interface {
 	Close() error
 }
 
+// closeRetryDrainTimeout bounds how long AsyncProducer.Close waits for
+// in-flight retry goroutines spawned by propagateError to observe closing
+// and return, before closing the underlying sarama producer regardless.
+const closeRetryDrainTimeout = 5 * time.Second
+
 // AsyncProducer defines a async Kafka producer.
 type AsyncProducer struct {
 	cfg         *sarama.Config
@@ -104,6 +164,9 @@ type AsyncProducer struct {
 	tag         opentracing.Tag
 	enc         encoding.EncodeFunc
 	contentType string
+	retry       *retryPolicy
+	closing     chan struct{}
+	retryWG     sync.WaitGroup
 }
 
 // Send a message to a topic.
@@ -111,13 +174,13 @@ func (ap *AsyncProducer) Send(ctx context.Context, msg *Message) error {
 	sp, _ := trace.ChildSpan(ctx, trace.ComponentOpName(producerComponent, msg.topic),
 		producerComponent, ext.SpanKindProducer, ap.tag,
 		opentracing.Tag{Key: "topic", Value: msg.topic})
-	pm, err := ap.createProducerMessage(ctx, msg, sp)
+	pm, err := createProducerMessage(ctx, msg, sp, ap.enc, ap.contentType)
 	if err != nil {
 		CountMessageCreationErrorsInc(msg.topic)
 		trace.SpanError(sp)
 		return err
 	}
-	CountMessagesSentInc(msg.topic)
+	CountMessagesSentInc(msg.topic, producerTypeAsync)
 	ap.prod.Input() <- pm
 	trace.SpanSuccess(sp)
 	return nil
@@ -128,47 +191,158 @@ func (ap *AsyncProducer) Error() <-chan error {
 	return ap.chErr
 }
 
-// Close gracefully the producer.
+// Close gracefully the producer, first waiting up to closeRetryDrainTimeout
+// for any in-flight retry goroutines spawned by propagateError to observe
+// closing and give up, so none of them can send on ap.prod.Input() after
+// ap.prod.Close() has been called - which sarama documents as a panic.
 func (ap *AsyncProducer) Close() error {
+	close(ap.closing)
+
+	drained := make(chan struct{})
+	go func() {
+		ap.retryWG.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+	case <-time.After(closeRetryDrainTimeout):
+	}
+
 	err := ap.prod.Close()
 	if err != nil {
-		return fmt.Errorf("failed to close sync producer: %w", err)
+		return fmt.Errorf("failed to close async producer: %w", err)
 	}
 	return nil
 }
 
 func (ap *AsyncProducer) propagateError() {
 	for pe := range ap.prod.Errors() {
-		CountMessageSendErrorsInc(pe.Msg.Topic)
+		CountMessageSendErrorsInc(pe.Msg.Topic, producerTypeAsync)
+
+		if ap.retry != nil && ap.retry.shouldRetry(pe) {
+			attempt := incrementRetryMetadata(pe.Msg)
+			if attempt <= ap.retry.max {
+				messageRetries.WithLabelValues(pe.Msg.Topic).Inc()
+				ap.retryWG.Add(1)
+				go func() {
+					defer ap.retryWG.Done()
+					ap.retry.schedule(attempt, func() {
+						select {
+						case <-ap.closing:
+							return
+						default:
+						}
+						ap.prod.Input() <- pe.Msg
+					})
+				}()
+				continue
+			}
+		} else if ap.retry != nil {
+			countNonRetryableErrors.WithLabelValues(pe.Msg.Topic, classify(pe)).Inc()
+		}
+
 		ap.chErr <- fmt.Errorf("failed to send message: %w", pe)
 	}
 }
 
-func (ap *AsyncProducer) createProducerMessage(ctx context.Context, msg *Message, sp opentracing.Span) (*sarama.ProducerMessage, error) {
+// SyncProducer defines a synchronous Kafka producer, returning the actual delivery
+// outcome (error, partition and offset) to the caller instead of propagating it
+// asynchronously on an error channel.
+type SyncProducer struct {
+	cfg         *sarama.Config
+	prod        sarama.SyncProducer
+	tag         opentracing.Tag
+	enc         encoding.EncodeFunc
+	contentType string
+}
+
+// Send a message to a topic and block until the broker has acknowledged the delivery
+// (or rejected it), returning the assigned partition and offset on success.
+func (sp *SyncProducer) Send(ctx context.Context, msg *Message) (partition int32, offset int64, err error) {
+	span, _ := trace.ChildSpan(ctx, trace.ComponentOpName(producerComponent, msg.topic),
+		producerComponent, ext.SpanKindProducer, sp.tag,
+		opentracing.Tag{Key: "topic", Value: msg.topic})
+	pm, err := createProducerMessage(ctx, msg, span, sp.enc, sp.contentType)
+	if err != nil {
+		CountMessageCreationErrorsInc(msg.topic)
+		trace.SpanError(span)
+		return 0, 0, err
+	}
+
+	partition, offset, err = sp.prod.SendMessage(pm)
+	if err != nil {
+		CountMessageSendErrorsInc(msg.topic, producerTypeSync)
+		trace.SpanError(span)
+		return 0, 0, fmt.Errorf("failed to send message: %w", err)
+	}
+	CountMessagesSentInc(msg.topic, producerTypeSync)
+	trace.SpanSuccess(span)
+	return partition, offset, nil
+}
+
+// Close gracefully the producer.
+func (sp *SyncProducer) Close() error {
+	err := sp.prod.Close()
+	if err != nil {
+		return fmt.Errorf("failed to close sync producer: %w", err)
+	}
+	return nil
+}
+
+// reservedHeaders are the correlation/content-type keys Patron itself stamps
+// on every produced message, which application headers may not override. The
+// keys the tracer injects are not listed here, so they are not checked.
+var reservedHeaders = map[string]struct{}{
+	encoding.ContentTypeHeader: {},
+	correlation.HeaderID:       {},
+}
+
+func createProducerMessage(ctx context.Context, msg *Message, sp opentracing.Span, enc encoding.EncodeFunc, contentType string) (*sarama.ProducerMessage, error) {
 	c := kafkaHeadersCarrier{}
 	err := sp.Tracer().Inject(sp.Context(), opentracing.TextMap, &c)
 	if err != nil {
 		return nil, fmt.Errorf("failed to inject tracing headers: %w", err)
 	}
-	c.Set(encoding.ContentTypeHeader, ap.contentType)
+	c.Set(encoding.ContentTypeHeader, contentType)
 
 	var saramaKey sarama.Encoder
-	if msg.key != nil {
+	switch {
+	case msg.key != nil:
 		saramaKey = sarama.StringEncoder(*msg.key)
+	case len(msg.keyBytes) > 0:
+		saramaKey = sarama.ByteEncoder(msg.keyBytes)
 	}
 
-	b, err := ap.enc(msg.body)
+	var b []byte
+	if msg.encoder != nil {
+		b, err = msg.encoder.Encode()
+	} else {
+		b, err = enc(msg.body)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to encode message body")
+		return nil, fmt.Errorf("failed to encode message body: %w", err)
 	}
 
 	c.Set(correlation.HeaderID, correlation.IDFromContext(ctx))
-	return &sarama.ProducerMessage{
+
+	for k, v := range msg.headers {
+		if _, reserved := reservedHeaders[k]; reserved {
+			return nil, fmt.Errorf("header %q is reserved and cannot be overridden", k)
+		}
+		c.Set(k, v)
+	}
+
+	pm := &sarama.ProducerMessage{
 		Topic:   msg.topic,
 		Key:     saramaKey,
 		Value:   sarama.ByteEncoder(b),
 		Headers: c,
-	}, nil
+	}
+	if msg.partition != nil {
+		pm.Partition = *msg.partition
+	}
+	stampPipelineCheckpoint(pm, serviceName, msg.topic)
+	return pm, nil
 }
 
 type kafkaHeadersCarrier []sarama.RecordHeader
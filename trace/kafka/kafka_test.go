@@ -0,0 +1,27 @@
+package kafka
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewMessageWithKeyBytes(t *testing.T) {
+	m, err := NewMessageWithKeyBytes("topic", "body", []byte{0x1, 0x2})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x1, 0x2}, m.keyBytes)
+
+	_, err = NewMessageWithKeyBytes("topic", "body", nil)
+	assert.Error(t, err)
+}
+
+func TestMessage_WithHeaders(t *testing.T) {
+	m := NewMessage("topic", "body").WithHeader("tenant", "acme").WithHeaders(map[string]string{"schema-version": "2"})
+	assert.Equal(t, "acme", m.headers["tenant"])
+	assert.Equal(t, "2", m.headers["schema-version"])
+}
+
+func TestMessage_WithPartition(t *testing.T) {
+	m := NewMessage("topic", "body").WithPartition(3)
+	assert.Equal(t, int32(3), *m.partition)
+}
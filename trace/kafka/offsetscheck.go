@@ -0,0 +1,119 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Shopify/sarama"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var countOffsetsInitialized *prometheus.CounterVec
+
+func init() {
+	countOffsetsInitialized = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "component",
+			Subsystem: "kafka_consumer_group",
+			Name:      "offsets_initialized",
+			Help:      "Partitions a ConsumerGroupOffsetsChecker found without a committed offset and initialized, classified by group and topic",
+		}, []string{"group", "topic"},
+	)
+	prometheus.MustRegister(countOffsetsInitialized)
+}
+
+// OffsetsChecker gates ConsumerBuilder.Create's Component.Run from starting
+// message dispatch until it returns, letting a consumer group guarantee its
+// offsets exist before it ever calls sarama.ConsumerGroup.Consume.
+type OffsetsChecker interface {
+	Check(ctx context.Context, cfg *sarama.Config, brokers []string, group string, topics []string) error
+}
+
+// noopOffsetsChecker is the default OffsetsChecker: it does nothing, leaving
+// offset resolution entirely to sarama's own OffsetNewest/OffsetOldest
+// behavior on first join.
+type noopOffsetsChecker struct{}
+
+// Check implements OffsetsChecker.
+func (noopOffsetsChecker) Check(context.Context, *sarama.Config, []string, string, []string) error {
+	return nil
+}
+
+// ConsumerGroupOffsetsChecker is an OffsetsChecker that, for every partition
+// of every topic, ensures a committed offset for group already exists -
+// initializing it at initialOffset if it doesn't. This closes the
+// well-known race where messages produced immediately after a consumer
+// group's first subscription are lost, because OffsetNewest only gets
+// resolved the first time the group actually joins and a produce in
+// between is never seen.
+type ConsumerGroupOffsetsChecker struct {
+	initialOffset int64
+}
+
+// NewConsumerGroupOffsetsChecker creates a ConsumerGroupOffsetsChecker that
+// initializes any partition missing a committed offset to initialOffset
+// (typically sarama.OffsetNewest or sarama.OffsetOldest).
+func NewConsumerGroupOffsetsChecker(initialOffset int64) *ConsumerGroupOffsetsChecker {
+	return &ConsumerGroupOffsetsChecker{initialOffset: initialOffset}
+}
+
+// Check implements OffsetsChecker.
+func (c *ConsumerGroupOffsetsChecker) Check(_ context.Context, cfg *sarama.Config, brokers []string, group string, topics []string) error {
+	client, err := sarama.NewClient(brokers, cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create client to check consumer group offsets: %w", err)
+	}
+	defer client.Close()
+
+	admin, err := sarama.NewClusterAdminFromClient(client)
+	if err != nil {
+		return fmt.Errorf("failed to create cluster admin to check consumer group offsets: %w", err)
+	}
+	defer admin.Close()
+
+	topicPartitions := make(map[string][]int32, len(topics))
+	for _, topic := range topics {
+		partitions, err := client.Partitions(topic)
+		if err != nil {
+			return fmt.Errorf("failed to list partitions for topic %q: %w", topic, err)
+		}
+		topicPartitions[topic] = partitions
+	}
+
+	committed, err := admin.ListConsumerGroupOffsets(group, topicPartitions)
+	if err != nil {
+		return fmt.Errorf("failed to list committed offsets for group %q: %w", group, err)
+	}
+
+	om, err := sarama.NewOffsetManagerFromClient(group, client)
+	if err != nil {
+		return fmt.Errorf("failed to create offset manager to check consumer group offsets: %w", err)
+	}
+	defer om.Close()
+
+	for topic, partitions := range topicPartitions {
+		for _, partition := range partitions {
+			block := committed.GetBlock(topic, partition)
+			if block != nil && block.Offset >= 0 {
+				continue
+			}
+
+			initial, err := client.GetOffset(topic, partition, c.initialOffset)
+			if err != nil {
+				return fmt.Errorf("failed to resolve initial offset for %s/%d: %w", topic, partition, err)
+			}
+
+			pom, err := om.ManagePartition(topic, partition)
+			if err != nil {
+				return fmt.Errorf("failed to manage %s/%d to initialize its offset: %w", topic, partition, err)
+			}
+			pom.MarkOffset(initial, "")
+			if err := pom.Close(); err != nil {
+				return fmt.Errorf("failed to commit initialized offset for %s/%d: %w", topic, partition, err)
+			}
+			countOffsetsInitialized.WithLabelValues(group, topic).Inc()
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,155 @@
+package kafka
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Shopify/sarama"
+	"github.com/beatlabs/patron/correlation"
+	"github.com/beatlabs/patron/trace"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+)
+
+// WrapSyncProducer decorates an already-constructed sarama.SyncProducer with
+// Patron's tracing conventions, for callers who build their own
+// sarama.ProducerMessage values directly instead of going through
+// Message/Builder. Every SendMessage/SendMessages call injects the current
+// span context and correlation id into the message's Headers, and reports a
+// SpanKindProducer span tagged with topic/partition/offset.
+func WrapSyncProducer(cfg *sarama.Config, prod sarama.SyncProducer) sarama.SyncProducer {
+	return &wrappedSyncProducer{SyncProducer: prod}
+}
+
+type wrappedSyncProducer struct {
+	sarama.SyncProducer
+}
+
+func (p *wrappedSyncProducer) SendMessage(msg *sarama.ProducerMessage) (partition int32, offset int64, err error) {
+	sp := startRawProducerSpan(context.Background(), msg)
+	partition, offset, err = p.SyncProducer.SendMessage(msg)
+	finishRawProducerSpan(sp, partition, offset, err)
+	return partition, offset, err
+}
+
+func (p *wrappedSyncProducer) SendMessages(msgs []*sarama.ProducerMessage) error {
+	spans := make([]opentracing.Span, len(msgs))
+	for i, msg := range msgs {
+		spans[i] = startRawProducerSpan(context.Background(), msg)
+	}
+	err := p.SyncProducer.SendMessages(msgs)
+	for i, msg := range msgs {
+		finishRawProducerSpan(spans[i], msg.Partition, msg.Offset, err)
+	}
+	return err
+}
+
+// WrapAsyncProducer decorates an already-constructed sarama.AsyncProducer
+// with Patron's tracing conventions, the async equivalent of
+// WrapSyncProducer. A span is started for every message written to Input()
+// and finished as soon as the outcome is known: on the Successes()/Errors()
+// channel if cfg enables them, or immediately after handoff otherwise -
+// mirroring how AsyncProducer.Send reports success without waiting for an
+// acknowledgement when those channels aren't in use.
+func WrapAsyncProducer(cfg *sarama.Config, prod sarama.AsyncProducer) sarama.AsyncProducer {
+	p := &wrappedAsyncProducer{
+		AsyncProducer: prod,
+		input:         make(chan *sarama.ProducerMessage),
+		spans:         make(map[*sarama.ProducerMessage]opentracing.Span),
+	}
+	if cfg.Producer.Return.Successes {
+		p.successes = make(chan *sarama.ProducerMessage)
+		go p.dispatchSuccesses()
+	}
+	if cfg.Producer.Return.Errors {
+		p.errors = make(chan *sarama.ProducerError)
+		go p.dispatchErrors()
+	}
+	go p.dispatchInput()
+	return p
+}
+
+type wrappedAsyncProducer struct {
+	sarama.AsyncProducer
+	input     chan *sarama.ProducerMessage
+	successes chan *sarama.ProducerMessage
+	errors    chan *sarama.ProducerError
+
+	mu    sync.Mutex
+	spans map[*sarama.ProducerMessage]opentracing.Span
+}
+
+func (p *wrappedAsyncProducer) Input() chan<- *sarama.ProducerMessage { return p.input }
+
+func (p *wrappedAsyncProducer) Successes() <-chan *sarama.ProducerMessage { return p.successes }
+
+func (p *wrappedAsyncProducer) Errors() <-chan *sarama.ProducerError { return p.errors }
+
+func (p *wrappedAsyncProducer) dispatchInput() {
+	trackOutcome := p.successes != nil || p.errors != nil
+	for msg := range p.input {
+		sp := startRawProducerSpan(context.Background(), msg)
+		if trackOutcome {
+			p.mu.Lock()
+			p.spans[msg] = sp
+			p.mu.Unlock()
+		} else {
+			trace.SpanSuccess(sp)
+		}
+		p.AsyncProducer.Input() <- msg
+	}
+}
+
+func (p *wrappedAsyncProducer) takeSpan(msg *sarama.ProducerMessage) opentracing.Span {
+	p.mu.Lock()
+	sp := p.spans[msg]
+	delete(p.spans, msg)
+	p.mu.Unlock()
+	return sp
+}
+
+func (p *wrappedAsyncProducer) dispatchSuccesses() {
+	for msg := range p.AsyncProducer.Successes() {
+		if sp := p.takeSpan(msg); sp != nil {
+			finishRawProducerSpan(sp, msg.Partition, msg.Offset, nil)
+		}
+		p.successes <- msg
+	}
+	close(p.successes)
+}
+
+func (p *wrappedAsyncProducer) dispatchErrors() {
+	for perr := range p.AsyncProducer.Errors() {
+		if sp := p.takeSpan(perr.Msg); sp != nil {
+			finishRawProducerSpan(sp, perr.Msg.Partition, perr.Msg.Offset, perr.Err)
+		}
+		p.errors <- perr
+	}
+	close(p.errors)
+}
+
+// startRawProducerSpan starts a SpanKindProducer span for msg and injects
+// the span context and correlation id into msg.Headers in place.
+func startRawProducerSpan(ctx context.Context, msg *sarama.ProducerMessage) opentracing.Span {
+	sp, _ := trace.ChildSpan(ctx, trace.ComponentOpName(producerComponent, msg.Topic),
+		producerComponent, ext.SpanKindProducer,
+		opentracing.Tag{Key: "topic", Value: msg.Topic},
+		opentracing.Tag{Key: "partition", Value: msg.Partition},
+	)
+
+	c := kafkaHeadersCarrier(msg.Headers)
+	if err := sp.Tracer().Inject(sp.Context(), opentracing.TextMap, &c); err == nil {
+		c.Set(correlation.HeaderID, correlation.IDFromContext(ctx))
+	}
+	msg.Headers = c
+	stampPipelineCheckpoint(msg, serviceName, msg.Topic)
+
+	return sp
+}
+
+// finishRawProducerSpan tags sp with the outcome of sending msg and finishes it.
+func finishRawProducerSpan(sp opentracing.Span, partition int32, offset int64, err error) {
+	sp.SetTag("partition", partition)
+	sp.SetTag("offset", offset)
+	trace.SpanComplete(sp, err)
+}
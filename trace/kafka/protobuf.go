@@ -0,0 +1,75 @@
+package kafka
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/beatlabs/patron/encoding"
+	"google.golang.org/protobuf/proto"
+)
+
+// ProtobufContentType is the content-type header value ConsumerBuilder
+// looks for to route decoding through NewProtobufDecoder, the Protobuf
+// counterpart of AvroContentType.
+const ProtobufContentType = "application/vnd.confluent.protobuf"
+
+// NewProtobufDecoder returns an encoding.DecodeRawFunc that strips
+// Confluent's wire framing off data - a magic byte, a big-endian schema ID
+// and a varint-prefixed array of message indexes selecting the nested
+// message type a .proto file with more than one top-level message
+// describes - checks that the schema ID resolves in registry, and
+// Protobuf-unmarshals the remaining payload into v, which must implement
+// proto.Message.
+func NewProtobufDecoder(registry SchemaRegistry) (encoding.DecodeRawFunc, error) {
+	if registry == nil {
+		return nil, errRegistryRequired
+	}
+
+	return func(data []byte, v interface{}) error {
+		msg, ok := v.(proto.Message)
+		if !ok {
+			return fmt.Errorf("protobuf decode target %T does not implement proto.Message", v)
+		}
+
+		id, ok := confluentFrame(data)
+		if !ok {
+			return fmt.Errorf("data is not confluent-framed protobuf: %d bytes", len(data))
+		}
+
+		if _, err := registry.Schema(id); err != nil {
+			return fmt.Errorf("failed to fetch protobuf schema %d: %w", id, err)
+		}
+
+		payload, err := skipMessageIndexes(data[avroFramingSize:])
+		if err != nil {
+			return fmt.Errorf("failed to parse protobuf message indexes for schema %d: %w", id, err)
+		}
+
+		if err := proto.Unmarshal(payload, msg); err != nil {
+			return fmt.Errorf("failed to unmarshal protobuf payload for schema %d: %w", id, err)
+		}
+		return nil
+	}, nil
+}
+
+// skipMessageIndexes consumes the varint-encoded array of message indexes
+// Confluent's wire format prefixes onto a Protobuf payload and returns the
+// remaining bytes, the actual Protobuf-encoded message. A leading count of
+// zero is the spec's shorthand for the single-message-type case, which this
+// loop already handles correctly by consuming no further index varints.
+func skipMessageIndexes(data []byte) ([]byte, error) {
+	count, n := binary.Varint(data)
+	if n <= 0 {
+		return nil, fmt.Errorf("invalid message index count")
+	}
+	data = data[n:]
+
+	for i := int64(0); i < count; i++ {
+		_, n := binary.Varint(data)
+		if n <= 0 {
+			return nil, fmt.Errorf("invalid message index entry %d", i)
+		}
+		data = data[n:]
+	}
+	return data, nil
+}
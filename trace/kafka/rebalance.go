@@ -0,0 +1,60 @@
+package kafka
+
+import (
+	"sort"
+
+	"github.com/Shopify/sarama"
+)
+
+// copartitionedStrategy is a sarama.BalanceStrategy that assigns the same partition
+// number of every subscribed topic to the same consumer group member, so that topics
+// meant to be joined (e.g. a changelog and its source topic) stay colocated and any
+// local state built from them remains consistent.
+type copartitionedStrategy struct{}
+
+// CoPartitioningStrategy returns a sarama.BalanceStrategy that assigns the
+// same partition number of every subscribed topic to the same consumer
+// group member, mirroring goka's copartitioning rebalancer. Pass it to
+// ConsumerBuilder.WithRebalanceStrategy, or use WithCopartitionedRebalance
+// as a shorthand.
+func CoPartitioningStrategy() sarama.BalanceStrategy {
+	return &copartitionedStrategy{}
+}
+
+// Name implements sarama.BalanceStrategy.
+func (s *copartitionedStrategy) Name() string {
+	return "copartitioned"
+}
+
+// Plan implements sarama.BalanceStrategy by distributing partition index i of every
+// topic to the i-th member (wrapping around when there are more partitions than members).
+func (s *copartitionedStrategy) Plan(members map[string]sarama.ConsumerGroupMemberMetadata, topics map[string][]int32) (sarama.BalanceStrategyPlan, error) {
+	plan := make(sarama.BalanceStrategyPlan, len(members))
+
+	memberIDs := make([]string, 0, len(members))
+	for id := range members {
+		memberIDs = append(memberIDs, id)
+	}
+	if len(memberIDs) == 0 {
+		return plan, nil
+	}
+	sort.Strings(memberIDs)
+
+	maxPartitions := 0
+	for _, partitions := range topics {
+		if len(partitions) > maxPartitions {
+			maxPartitions = len(partitions)
+		}
+	}
+
+	for i := 0; i < maxPartitions; i++ {
+		member := memberIDs[i%len(memberIDs)]
+		for topic, partitions := range topics {
+			if i >= len(partitions) {
+				continue
+			}
+			plan.Add(member, topic, partitions[i])
+		}
+	}
+	return plan, nil
+}
@@ -0,0 +1,71 @@
+package kafka
+
+import (
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCoPartitioningStrategy_Plan_DeterministicAcrossMemberOrder(t *testing.T) {
+	members := map[string]sarama.ConsumerGroupMemberMetadata{
+		"member-c": {},
+		"member-a": {},
+		"member-b": {},
+	}
+	topics := map[string][]int32{
+		"source":    {0, 1, 2},
+		"changelog": {0, 1, 2},
+	}
+
+	strategy := CoPartitioningStrategy()
+
+	var first sarama.BalanceStrategyPlan
+	for i := 0; i < 10; i++ {
+		plan, err := strategy.Plan(members, topics)
+		require.NoError(t, err)
+		if i == 0 {
+			first = plan
+			continue
+		}
+		assert.Equal(t, first, plan, "Plan must be a deterministic function of the member set, not call order")
+	}
+}
+
+func TestCoPartitioningStrategy_Plan_ColocatesTopics(t *testing.T) {
+	members := map[string]sarama.ConsumerGroupMemberMetadata{
+		"member-a": {},
+		"member-b": {},
+	}
+	topics := map[string][]int32{
+		"source":    {0, 1},
+		"changelog": {0, 1},
+	}
+
+	plan, err := strategy().Plan(members, topics)
+	require.NoError(t, err)
+
+	for partition := int32(0); partition < 2; partition++ {
+		sourceMember := memberFor(t, plan, "source", partition)
+		changelogMember := memberFor(t, plan, "changelog", partition)
+		assert.Equal(t, sourceMember, changelogMember, "partition %d of both topics must go to the same member", partition)
+	}
+}
+
+func strategy() sarama.BalanceStrategy {
+	return CoPartitioningStrategy()
+}
+
+func memberFor(t *testing.T, plan sarama.BalanceStrategyPlan, topic string, partition int32) string {
+	t.Helper()
+	for member, assignment := range plan {
+		for _, p := range assignment[topic] {
+			if p == partition {
+				return member
+			}
+		}
+	}
+	t.Fatalf("no member assigned %s/%d", topic, partition)
+	return ""
+}
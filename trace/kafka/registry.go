@@ -0,0 +1,137 @@
+package kafka
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+
+	"github.com/beatlabs/patron/cache/lru"
+	"github.com/beatlabs/patron/errors"
+)
+
+// defaultSchemaCacheSize bounds how many distinct schema IDs registryClient
+// keeps resolved in memory, so a consumer subscribed to many schemas can't
+// grow the cache without bound.
+const defaultSchemaCacheSize = 1024
+
+// SchemaRegistry registers and resolves Avro schemas against a Confluent
+// Schema Registry, as used by NewAvroEncoder/NewAvroDecoder.
+type SchemaRegistry interface {
+	// Register registers schema under subject, returning its schema ID. A
+	// schema already registered for subject returns the existing ID.
+	Register(subject, schema string) (int, error)
+	// Schema returns the schema registered under id.
+	Schema(id int) (string, error)
+}
+
+// registryClient is a SchemaRegistry backed by Confluent's Schema Registry
+// HTTP API, caching both directions of the subject/schema <-> ID mapping
+// so a busy producer or consumer doesn't round-trip for every message.
+type registryClient struct {
+	baseURL string
+	http    *http.Client
+
+	mu          sync.Mutex
+	idBySubject map[string]int
+	schemaByID  *lru.Cache
+}
+
+// NewSchemaRegistryClient returns a SchemaRegistry backed by the Confluent
+// Schema Registry at baseURL. Resolved schemas are kept in a bounded LRU
+// cache, so a busy consumer subscribed to many schemas doesn't round-trip
+// for every message without growing memory use without bound.
+func NewSchemaRegistryClient(baseURL string) SchemaRegistry {
+	schemaByID, _ := lru.New(defaultSchemaCacheSize)
+	return &registryClient{
+		baseURL:     baseURL,
+		http:        &http.Client{},
+		idBySubject: make(map[string]int),
+		schemaByID:  schemaByID,
+	}
+}
+
+type registerRequest struct {
+	Schema string `json:"schema"`
+}
+
+type registerResponse struct {
+	ID int `json:"id"`
+}
+
+func (c *registryClient) Register(subject, schema string) (int, error) {
+	key := subject + "\x00" + schema
+
+	c.mu.Lock()
+	if id, ok := c.idBySubject[key]; ok {
+		c.mu.Unlock()
+		return id, nil
+	}
+	c.mu.Unlock()
+
+	body, err := json.Marshal(registerRequest{Schema: schema})
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal schema registration request: %w", err)
+	}
+
+	u := fmt.Sprintf("%s/subjects/%s/versions", c.baseURL, url.PathEscape(subject))
+	resp, err := c.http.Post(u, "application/vnd.schemaregistry.v1+json", bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("failed to register schema for subject %s: %w", subject, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(resp.Body)
+		return 0, fmt.Errorf("schema registry rejected subject %s registration (%d): %s", subject, resp.StatusCode, b)
+	}
+
+	var out registerResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return 0, fmt.Errorf("failed to decode schema registration response: %w", err)
+	}
+
+	c.mu.Lock()
+	c.idBySubject[key] = out.ID
+	c.mu.Unlock()
+	_ = c.schemaByID.Set(strconv.Itoa(out.ID), schema)
+
+	return out.ID, nil
+}
+
+type schemaResponse struct {
+	Schema string `json:"schema"`
+}
+
+func (c *registryClient) Schema(id int) (string, error) {
+	if schema, ok, _ := c.schemaByID.Get(strconv.Itoa(id)); ok {
+		return schema.(string), nil
+	}
+
+	u := fmt.Sprintf("%s/schemas/ids/%d", c.baseURL, id)
+	resp, err := c.http.Get(u)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch schema %d: %w", id, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("schema registry has no schema %d (%d): %s", id, resp.StatusCode, b)
+	}
+
+	var out schemaResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", fmt.Errorf("failed to decode schema %d response: %w", id, err)
+	}
+
+	_ = c.schemaByID.Set(strconv.Itoa(id), out.Schema)
+
+	return out.Schema, nil
+}
+
+var errRegistryRequired = errors.New("schema registry is required")
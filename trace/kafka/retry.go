@@ -0,0 +1,121 @@
+package kafka
+
+import (
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// retryAttemptHeader carries the current retry attempt number on a re-enqueued
+// sarama.ProducerMessage, so propagateError can tell how many times it has been tried.
+const retryAttemptHeader = "patron-retry-attempt"
+
+var messageRetries *prometheus.CounterVec
+var countNonRetryableErrors *prometheus.CounterVec
+
+func init() {
+	messageRetries = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "component",
+			Subsystem: "kafka_async_producer",
+			Name:      "message_retries",
+			Help:      "Message retries counter, classified by topic",
+		}, []string{"topic"},
+	)
+	countNonRetryableErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "component",
+			Subsystem: "kafka_async_producer",
+			Name:      "message_non_retryable_errors",
+			Help:      "Non-retryable send errors counter, classified by topic and error classification",
+		}, []string{"topic", "classification"},
+	)
+	prometheus.MustRegister(messageRetries, countNonRetryableErrors)
+}
+
+// classify labels a non-retryable sarama error for the message_non_retryable_errors metric.
+func classify(pe *sarama.ProducerError) string {
+	switch pe.Err {
+	case sarama.ErrInvalidMessage, sarama.ErrMessageTooLarge:
+		return "message-too-large"
+	case sarama.ErrTopicAuthorizationFailed, sarama.ErrClusterAuthorizationFailed, sarama.ErrSASLAuthenticationFailed:
+		return "auth"
+	case sarama.ErrUnknownTopicOrPartition, sarama.ErrInvalidTopic:
+		return "invalid-topic"
+	default:
+		return "other"
+	}
+}
+
+// retryPolicy re-enqueues failed messages with capped exponential backoff, instead of
+// propagating every sarama.ProducerError straight to the caller's error channel.
+type retryPolicy struct {
+	max     int
+	initial time.Duration
+	cap     time.Duration
+	jitter  float64
+}
+
+// newRetryPolicy constructs a retryPolicy; it does not validate its
+// arguments, which WithRetryPolicy checks.
+func newRetryPolicy(max int, initial, maxBackoff time.Duration, jitter float64) *retryPolicy {
+	return &retryPolicy{max: max, initial: initial, cap: maxBackoff, jitter: jitter}
+}
+
+// shouldRetry classifies the broker error as transient (safe to retry at Patron's
+// layer) or not. Auth failures, oversized messages and unknown topics are never retried.
+func (r *retryPolicy) shouldRetry(pe *sarama.ProducerError) bool {
+	switch pe.Err {
+	case sarama.ErrLeaderNotAvailable,
+		sarama.ErrNotLeaderForPartition,
+		sarama.ErrRequestTimedOut,
+		sarama.ErrNetworkException,
+		sarama.ErrBrokerNotAvailable:
+		return true
+	default:
+		return false
+	}
+}
+
+// backoff computes the capped, jittered exponential delay for the given attempt
+// (1-indexed): initial * 2^(attempt-1), capped at r.cap, multiplied by 1 ± jitter.
+func (r *retryPolicy) backoff(attempt int) time.Duration {
+	d := r.initial * time.Duration(1<<uint(attempt-1))
+	if d > r.cap {
+		d = r.cap
+	}
+	if r.jitter > 0 {
+		delta := (rand.Float64()*2 - 1) * r.jitter //nolint:gosec
+		d = time.Duration(float64(d) * (1 + delta))
+	}
+	return d
+}
+
+// schedule runs fn after the backoff delay for attempt has elapsed.
+func (r *retryPolicy) schedule(attempt int, fn func()) {
+	time.Sleep(r.backoff(attempt))
+	fn()
+}
+
+// incrementRetryMetadata bumps and returns the retry attempt counter stamped on msg's
+// headers, so repeated failures of the same message can be tracked across retries.
+func incrementRetryMetadata(msg *sarama.ProducerMessage) int {
+	attempt := 1
+	for i, h := range msg.Headers {
+		if string(h.Key) == retryAttemptHeader {
+			if n, err := strconv.Atoi(string(h.Value)); err == nil {
+				attempt = n + 1
+			}
+			msg.Headers[i].Value = []byte(strconv.Itoa(attempt))
+			return attempt
+		}
+	}
+	msg.Headers = append(msg.Headers, sarama.RecordHeader{
+		Key:   []byte(retryAttemptHeader),
+		Value: []byte(strconv.Itoa(attempt)),
+	})
+	return attempt
+}
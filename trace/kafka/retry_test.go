@@ -0,0 +1,29 @@
+package kafka
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	r := newRetryPolicy(5, 100*time.Millisecond, time.Second, 0)
+	assert.Equal(t, 100*time.Millisecond, r.backoff(1))
+	assert.Equal(t, 200*time.Millisecond, r.backoff(2))
+	assert.Equal(t, 400*time.Millisecond, r.backoff(3))
+	assert.Equal(t, time.Second, r.backoff(10))
+}
+
+func TestRetryPolicy_ShouldRetry(t *testing.T) {
+	r := newRetryPolicy(1, time.Millisecond, time.Millisecond, 0)
+	assert.True(t, r.shouldRetry(&sarama.ProducerError{Err: sarama.ErrRequestTimedOut}))
+	assert.False(t, r.shouldRetry(&sarama.ProducerError{Err: sarama.ErrMessageTooLarge}))
+}
+
+func TestIncrementRetryMetadata(t *testing.T) {
+	msg := &sarama.ProducerMessage{Topic: "topic"}
+	assert.Equal(t, 1, incrementRetryMetadata(msg))
+	assert.Equal(t, 2, incrementRetryMetadata(msg))
+}
@@ -0,0 +1,96 @@
+package kafka
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// State is a Component's connection lifecycle state, observable via
+// Component.State and Component.Observe.
+type State int
+
+const (
+	// Connecting is the state between Run starting (or a session ending
+	// without error) and the next sarama.ConsumerGroup being created.
+	Connecting State = iota
+	// Connected means a consumer group session is currently active.
+	Connected
+	// Reconnecting means a session ended and Run is backing off before
+	// creating a new sarama.ConsumerGroup.
+	Reconnecting
+	// Stopped means Run has returned: ctx was cancelled, retries were
+	// exhausted, or the offsets check or consumer group creation failed.
+	Stopped
+)
+
+// String implements fmt.Stringer.
+func (s State) String() string {
+	switch s {
+	case Connecting:
+		return "connecting"
+	case Connected:
+		return "connected"
+	case Reconnecting:
+		return "reconnecting"
+	case Stopped:
+		return "stopped"
+	default:
+		return "unknown"
+	}
+}
+
+var consumerState *prometheus.GaugeVec
+
+func init() {
+	consumerState = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "component",
+			Subsystem: "kafka_consumer_group",
+			Name:      "state",
+			Help:      "Current connection state of a consumer group Component (0=connecting, 1=connected, 2=reconnecting, 3=stopped), classified by group",
+		}, []string{"group"},
+	)
+	prometheus.MustRegister(consumerState)
+}
+
+// stateTracker holds a Component's current State, reports it to Prometheus,
+// and fans out transitions to any channels registered via Component.Observe.
+type stateTracker struct {
+	group string
+
+	mu          sync.RWMutex
+	state       State
+	subscribers []chan State
+}
+
+func newStateTracker(group string) *stateTracker {
+	return &stateTracker{group: group, state: Connecting}
+}
+
+func (t *stateTracker) set(s State) {
+	t.mu.Lock()
+	t.state = s
+	subs := append([]chan State(nil), t.subscribers...)
+	t.mu.Unlock()
+
+	consumerState.WithLabelValues(t.group).Set(float64(s))
+	for _, ch := range subs {
+		select {
+		case ch <- s:
+		default:
+		}
+	}
+}
+
+func (t *stateTracker) get() State {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.state
+}
+
+func (t *stateTracker) observe(ch chan State) {
+	t.mu.Lock()
+	t.subscribers = append(t.subscribers, ch)
+	t.mu.Unlock()
+}
@@ -0,0 +1,241 @@
+// Package otel provides a trace.Provider that ships spans to an OpenTelemetry
+// collector over OTLP (or, via WithJaegerExporter/WithStdoutExporter, to a
+// Jaeger collector or stdout), bridged into the opentracing API the rest of
+// patron is instrumented with.
+package otel
+
+import (
+	"context"
+	"time"
+
+	"github.com/beatlabs/patron/errors"
+	"github.com/beatlabs/patron/trace"
+	opentracing "github.com/opentracing/opentracing-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelbridge "go.opentelemetry.io/otel/bridge/opentracing"
+	"go.opentelemetry.io/otel/exporters/jaeger"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+)
+
+const shutdownTimeout = 5 * time.Second
+
+// Protocol selects the wire protocol used to talk to the OTLP collector.
+type Protocol int
+
+const (
+	// ProtocolGRPC exports spans over OTLP/gRPC. This is the default.
+	ProtocolGRPC Protocol = iota
+	// ProtocolHTTP exports spans over OTLP/HTTP.
+	ProtocolHTTP
+)
+
+// exporterKind selects which backend Setup ships spans to. It is set by
+// whichever of WithJaegerExporter/WithStdoutExporter is used, or left at its
+// zero value to export over OTLP via Protocol.
+type exporterKind int
+
+const (
+	exporterOTLP exporterKind = iota
+	exporterJaeger
+	exporterStdout
+)
+
+// Option configures a Provider created by New.
+type Option func(*Provider) error
+
+// WithProtocol selects the OTLP wire protocol. It defaults to ProtocolGRPC.
+func WithProtocol(p Protocol) Option {
+	return func(pr *Provider) error {
+		pr.protocol = p
+		return nil
+	}
+}
+
+// WithInsecure disables transport security when talking to the collector.
+// It is intended for local development, where the collector is not fronted
+// by TLS.
+func WithInsecure() Option {
+	return func(pr *Provider) error {
+		pr.insecure = true
+		return nil
+	}
+}
+
+// WithSampler overrides the default sdktrace.AlwaysSample sampler.
+func WithSampler(s sdktrace.Sampler) Option {
+	return func(pr *Provider) error {
+		if s == nil {
+			return errors.New("sampler is nil")
+		}
+		pr.sampler = s
+		return nil
+	}
+}
+
+// WithResourceAttributes adds resource attributes, reported to the
+// collector alongside every span, on top of the service name and version
+// patron.Run already sets.
+func WithResourceAttributes(attrs ...attribute.KeyValue) Option {
+	return func(pr *Provider) error {
+		pr.attrs = append(pr.attrs, attrs...)
+		return nil
+	}
+}
+
+// WithJaegerExporter ships spans to a Jaeger collector's HTTP Thrift
+// endpoint instead of an OTLP collector, for services migrating off
+// trace.Setup's Jaeger-only tracer one at a time. endpoint overrides New's
+// endpoint argument, which is otherwise interpreted as the OTLP collector
+// address.
+func WithJaegerExporter(endpoint string) Option {
+	return func(pr *Provider) error {
+		if endpoint == "" {
+			return errors.New("jaeger collector endpoint is required")
+		}
+		pr.exporter = exporterJaeger
+		pr.endpoint = endpoint
+		return nil
+	}
+}
+
+// WithStdoutExporter writes spans to stdout instead of a collector, so
+// tests can assert on emitted spans without standing up a backend. It
+// makes New's endpoint argument irrelevant.
+func WithStdoutExporter() Option {
+	return func(pr *Provider) error {
+		pr.exporter = exporterStdout
+		return nil
+	}
+}
+
+// WithPropagators overrides the default W3C trace-context-plus-baggage
+// propagator with pp, composed in the given order, e.g. to add a
+// B3-compatible propagator (go.opentelemetry.io/contrib/propagators/b3)
+// ahead of or alongside the default for services talking to clients that
+// don't send traceparent/tracestate headers yet.
+func WithPropagators(pp ...propagation.TextMapPropagator) Option {
+	return func(pr *Provider) error {
+		if len(pp) == 0 {
+			return errors.New("at least one propagator is required")
+		}
+		pr.propagator = propagation.NewCompositeTextMapPropagator(pp...)
+		return nil
+	}
+}
+
+// Provider is a trace.Provider that exports spans to an OTLP collector, or
+// to a Jaeger or stdout exporter swapped in via WithJaegerExporter or
+// WithStdoutExporter.
+type Provider struct {
+	endpoint   string
+	protocol   Protocol
+	insecure   bool
+	exporter   exporterKind
+	sampler    sdktrace.Sampler
+	propagator propagation.TextMapPropagator
+	attrs      []attribute.KeyValue
+
+	tp *sdktrace.TracerProvider
+}
+
+var _ trace.Provider = (*Provider)(nil)
+
+// New returns an OTLP-backed trace.Provider that exports spans to the
+// collector listening at endpoint (host:port). endpoint is ignored when
+// WithStdoutExporter is given, and reinterpreted as a Jaeger collector
+// endpoint by WithJaegerExporter.
+func New(endpoint string, oo ...Option) (*Provider, error) {
+	p := &Provider{
+		endpoint:   endpoint,
+		protocol:   ProtocolGRPC,
+		sampler:    sdktrace.AlwaysSample(),
+		propagator: propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}),
+	}
+
+	for _, opt := range oo {
+		if err := opt(p); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.endpoint == "" && p.exporter != exporterStdout {
+		return nil, errors.New("endpoint is required")
+	}
+
+	return p, nil
+}
+
+// Setup installs this provider as the global opentracing tracer for name/ver.
+func (p *Provider) Setup(name, ver string) error {
+	ctx := context.Background()
+
+	exp, err := p.newExporter(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to create span exporter")
+	}
+
+	res, err := resource.New(ctx, resource.WithAttributes(append([]attribute.KeyValue{
+		semconv.ServiceNameKey.String(name),
+		semconv.ServiceVersionKey.String(ver),
+	}, p.attrs...)...))
+	if err != nil {
+		_ = exp.Shutdown(ctx)
+		return errors.Wrap(err, "failed to build resource")
+	}
+
+	p.tp = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(p.sampler),
+	)
+
+	otel.SetTracerProvider(p.tp)
+	otel.SetTextMapPropagator(p.propagator)
+	bridge, _ := otelbridge.NewTracerPair(p.tp.Tracer(name))
+	opentracing.SetGlobalTracer(bridge)
+	return nil
+}
+
+func (p *Provider) newExporter(ctx context.Context) (sdktrace.SpanExporter, error) {
+	switch p.exporter {
+	case exporterJaeger:
+		return jaeger.New(jaeger.WithCollectorEndpoint(jaeger.WithEndpoint(p.endpoint)))
+	case exporterStdout:
+		return stdouttrace.New()
+	default:
+		return p.newOTLPExporter(ctx)
+	}
+}
+
+func (p *Provider) newOTLPExporter(ctx context.Context) (sdktrace.SpanExporter, error) {
+	if p.protocol == ProtocolHTTP {
+		opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(p.endpoint)}
+		if p.insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		return otlptracehttp.New(ctx, opts...)
+	}
+
+	opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(p.endpoint)}
+	if p.insecure {
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	}
+	return otlptracegrpc.New(ctx, opts...)
+}
+
+// Close flushes and shuts down the underlying TracerProvider.
+func (p *Provider) Close() error {
+	if p.tp == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	return p.tp.Shutdown(ctx)
+}
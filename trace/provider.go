@@ -0,0 +1,13 @@
+package trace
+
+// Provider installs a tracing backend as the global tracer for a service.
+// Setup is implemented by Setup's default Jaeger path and may also be
+// implemented by alternative backends (e.g. trace/otel), so that
+// patron.WithTracer can swap the tracing backend without patron or any
+// instrumented package needing to know which one is in use.
+type Provider interface {
+	// Setup installs this provider as the global tracer for name/ver.
+	Setup(name, ver string) error
+	// Close flushes and shuts down the provider.
+	Close() error
+}
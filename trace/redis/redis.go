@@ -2,6 +2,7 @@ package redis
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 
 	"github.com/beatlabs/patron/trace"
@@ -35,28 +36,106 @@ func Span(ctx context.Context, opName, cmp, dbType, instance, stmt string,
 // Options wraps redis.Options for easier usage.
 type Options redis.Options
 
+// SentinelOptions configures a Client connecting through Redis Sentinel to a
+// monitored master/replica deployment, wrapping redis.FailoverOptions.
+type SentinelOptions struct {
+	MasterName       string
+	SentinelAddrs    []string
+	SentinelPassword string
+	Password         string
+	DB               int
+	MaxRetries       int
+	PoolSize         int
+	MinIdleConns     int
+	TLSConfig        *tls.Config
+}
+
+// ClusterOptions configures a Client connecting to a Redis Cluster deployment,
+// wrapping redis.ClusterOptions.
+type ClusterOptions struct {
+	Addrs          []string
+	Password       string
+	MaxRedirects   int
+	ReadOnly       bool
+	RouteByLatency bool
+	RouteRandomly  bool
+	MaxRetries     int
+	PoolSize       int
+	MinIdleConns   int
+	TLSConfig      *tls.Config
+}
+
 // Nil represents the error which is returned in case a key is not found.
 const Nil = redis.Nil
 
-// Client represents a connection with a Redis client.
+// Client represents a connection with a Redis deployment, be it a single node,
+// a Sentinel-monitored master/replica setup or a Cluster, behind a common
+// redis.UniversalClient interface so callers don't need to care which topology
+// is in use.
 type Client struct {
-	*redis.Client
+	rdb      redis.UniversalClient
+	instance string
 }
 
 func (c *Client) startSpan(ctx context.Context, opName, stmt string) (opentracing.Span, context.Context) {
-	return Span(ctx, opName, RedisComponent, RedisDBType, stmt, c.Options().Addr)
+	return Span(ctx, opName, RedisComponent, RedisDBType, c.instance, stmt)
 }
 
-// New returns a new Redis client.
+// Instance returns the address (or Sentinel master name / first Cluster
+// address) this Client was constructed with, for callers that want to tag
+// their own spans or metrics with it.
+func (c *Client) Instance() string {
+	return c.instance
+}
+
+// New returns a new Redis client talking to a single node.
 func New(opt Options) *Client {
 	clientOptions := redis.Options(opt)
-	return &Client{redis.NewClient(&clientOptions)}
+	return &Client{rdb: redis.NewClient(&clientOptions), instance: clientOptions.Addr}
+}
+
+// NewSentinel returns a new Redis client that discovers the current master via
+// Sentinel and fails over transparently when a new master is elected.
+func NewSentinel(opt SentinelOptions) *Client {
+	fo := &redis.FailoverOptions{
+		MasterName:       opt.MasterName,
+		SentinelAddrs:    opt.SentinelAddrs,
+		SentinelPassword: opt.SentinelPassword,
+		Password:         opt.Password,
+		DB:               opt.DB,
+		MaxRetries:       opt.MaxRetries,
+		PoolSize:         opt.PoolSize,
+		MinIdleConns:     opt.MinIdleConns,
+		TLSConfig:        opt.TLSConfig,
+	}
+	return &Client{rdb: redis.NewFailoverClient(fo), instance: opt.MasterName}
+}
+
+// NewCluster returns a new Redis client talking to a Redis Cluster deployment.
+func NewCluster(opt ClusterOptions) *Client {
+	co := &redis.ClusterOptions{
+		Addrs:          opt.Addrs,
+		Password:       opt.Password,
+		MaxRedirects:   opt.MaxRedirects,
+		ReadOnly:       opt.ReadOnly,
+		RouteByLatency: opt.RouteByLatency,
+		RouteRandomly:  opt.RouteRandomly,
+		MaxRetries:     opt.MaxRetries,
+		PoolSize:       opt.PoolSize,
+		MinIdleConns:   opt.MinIdleConns,
+		TLSConfig:      opt.TLSConfig,
+	}
+	instance := ""
+	if len(opt.Addrs) > 0 {
+		instance = opt.Addrs[0]
+	}
+	return &Client{rdb: redis.NewClusterClient(co), instance: instance}
 }
 
 // Do creates and processes a custom Cmd on the underlying Redis client.
 func (c *Client) Do(ctx context.Context, args ...interface{}) *redis.Cmd {
 	sp, _ := c.startSpan(ctx, "redis.Do", fmt.Sprintf("%v", args))
-	cmd := c.Client.Do(args...)
+	cmd := c.rdb.Do(args...)
 	trace.SpanComplete(sp, cmd.Err())
 	return cmd
 }
@@ -64,7 +143,7 @@ func (c *Client) Do(ctx context.Context, args ...interface{}) *redis.Cmd {
 // Close closes the connection to the underlying Redis client.
 func (c *Client) Close(ctx context.Context, args ...interface{}) error {
 	sp, _ := c.startSpan(ctx, "redis.Close", "")
-	err := c.Client.Close()
+	err := c.rdb.Close()
 	trace.SpanComplete(sp, err)
 	return err
 }
@@ -72,7 +151,7 @@ func (c *Client) Close(ctx context.Context, args ...interface{}) error {
 // Ping can be used to test whether a connection is still alive, or measure latency.
 func (c *Client) Ping(ctx context.Context) (string, error) {
 	sp, _ := c.startSpan(ctx, "redis.Ping", "")
-	cmd := c.Client.Ping()
+	cmd := c.rdb.Ping()
 	trace.SpanComplete(sp, cmd.Err())
 	return cmd.Result()
 }
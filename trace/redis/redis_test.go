@@ -10,6 +10,16 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+func TestNewSentinelAndCluster(t *testing.T) {
+	c := NewSentinel(SentinelOptions{MasterName: "mymaster", SentinelAddrs: []string{"localhost:26379"}})
+	assert.NotNil(t, c)
+	assert.Equal(t, "mymaster", c.instance)
+
+	cl := NewCluster(ClusterOptions{Addrs: []string{"localhost:7000", "localhost:7001"}})
+	assert.NotNil(t, cl)
+	assert.Equal(t, "localhost:7000", cl.instance)
+}
+
 func TestSpan(t *testing.T) {
 	mtr := mocktracer.New()
 	opentracing.SetGlobalTracer(mtr)
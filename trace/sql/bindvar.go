@@ -0,0 +1,108 @@
+package sql
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// bindType identifies a driver's placeholder style for positional arguments.
+type bindType int
+
+const (
+	// bindQuestion is the "?" style used by mysql and sqlite3, and the
+	// fallback for unrecognized or unknown drivers.
+	bindQuestion bindType = iota
+	// bindDollar is the "$1" style used by postgres/pgx/pq.
+	bindDollar
+	// bindColon is the ":1" style used by Oracle drivers.
+	bindColon
+	// bindAt is the "@p1" style used by Microsoft SQL Server drivers.
+	bindAt
+)
+
+// bindTypeForDriver maps a database/sql driver name, as registered via
+// sql.Register and passed to Open, to the positional-bindvar style it
+// expects. Unrecognized names fall back to bindQuestion.
+func bindTypeForDriver(driverName string) bindType {
+	switch strings.ToLower(driverName) {
+	case "postgres", "pgx", "pq", "cloudsqlpostgres":
+		return bindDollar
+	case "oci8", "ora", "goracle", "godror":
+		return bindColon
+	case "sqlserver", "mssql":
+		return bindAt
+	default:
+		return bindQuestion
+	}
+}
+
+// compileNamed rewrites every ":name" placeholder in query to the
+// positional bindvar style bt expects, in the order they appear, and
+// returns the rewritten query plus the names in that same order. A literal
+// "::" (e.g. postgres' type-cast operator) and colons inside single-quoted
+// string literals are copied through untouched.
+func compileNamed(query string, bt bindType) (string, []string) {
+	var (
+		b     strings.Builder
+		names []string
+		n     int
+	)
+
+	rq := []rune(query)
+	for i := 0; i < len(rq); i++ {
+		switch rq[i] {
+		case '\'':
+			b.WriteRune(rq[i])
+			for i++; i < len(rq); i++ {
+				b.WriteRune(rq[i])
+				if rq[i] == '\'' {
+					break
+				}
+			}
+		case ':':
+			if i+1 < len(rq) && rq[i+1] == ':' {
+				b.WriteString("::")
+				i++
+				continue
+			}
+
+			j := i + 1
+			for j < len(rq) && isNameRune(rq[j]) {
+				j++
+			}
+			if j == i+1 {
+				b.WriteRune(rq[i])
+				continue
+			}
+
+			n++
+			names = append(names, string(rq[i+1:j]))
+			b.WriteString(bindvarFor(bt, n))
+			i = j - 1
+		default:
+			b.WriteRune(rq[i])
+		}
+	}
+
+	return b.String(), names
+}
+
+// isNameRune reports whether r can appear in a ":name" placeholder.
+func isNameRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// bindvarFor renders the nth positional bindvar in bt's style.
+func bindvarFor(bt bindType, n int) string {
+	switch bt {
+	case bindDollar:
+		return "$" + strconv.Itoa(n)
+	case bindColon:
+		return ":" + strconv.Itoa(n)
+	case bindAt:
+		return "@p" + strconv.Itoa(n)
+	default:
+		return "?"
+	}
+}
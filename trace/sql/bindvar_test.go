@@ -0,0 +1,94 @@
+package sql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBindTypeForDriver(t *testing.T) {
+	type testcase struct {
+		driver string
+		want   bindType
+	}
+
+	var testcases = []testcase{
+		{"postgres", bindDollar},
+		{"pgx", bindDollar},
+		{"pq", bindDollar},
+		{"cloudsqlpostgres", bindDollar},
+		{"Postgres", bindDollar},
+		{"oci8", bindColon},
+		{"godror", bindColon},
+		{"sqlserver", bindAt},
+		{"mssql", bindAt},
+		{"mysql", bindQuestion},
+		{"sqlite3", bindQuestion},
+		{"", bindQuestion},
+	}
+
+	for _, tc := range testcases {
+		got := bindTypeForDriver(tc.driver)
+		assert.Equal(t, tc.want, got)
+	}
+}
+
+func TestCompileNamed(t *testing.T) {
+	type testcase struct {
+		query     string
+		bt        bindType
+		wantQuery string
+		wantNames []string
+	}
+
+	var testcases = []testcase{
+		{
+			"SELECT * FROM users WHERE id = :id",
+			bindQuestion,
+			"SELECT * FROM users WHERE id = ?",
+			[]string{"id"},
+		},
+		{
+			"SELECT * FROM users WHERE id = :id AND name = :name",
+			bindDollar,
+			"SELECT * FROM users WHERE id = $1 AND name = $2",
+			[]string{"id", "name"},
+		},
+		{
+			"INSERT INTO users (id) VALUES (:id)",
+			bindAt,
+			"INSERT INTO users (id) VALUES (@p1)",
+			[]string{"id"},
+		},
+		{
+			"SELECT '::' FROM users",
+			bindDollar,
+			"SELECT '::' FROM users",
+			nil,
+		},
+		{
+			"SELECT price::numeric FROM products WHERE id = :id",
+			bindDollar,
+			"SELECT price::numeric FROM products WHERE id = $1",
+			[]string{"id"},
+		},
+		{
+			"SELECT * FROM users WHERE name = 'has:colon' AND id = :id",
+			bindColon,
+			"SELECT * FROM users WHERE name = 'has:colon' AND id = :1",
+			[]string{"id"},
+		},
+		{
+			"SELECT * FROM users",
+			bindQuestion,
+			"SELECT * FROM users",
+			nil,
+		},
+	}
+
+	for _, tc := range testcases {
+		gotQuery, gotNames := compileNamed(tc.query, tc.bt)
+		assert.Equal(t, tc.wantQuery, gotQuery)
+		assert.Equal(t, tc.wantNames, gotNames)
+	}
+}
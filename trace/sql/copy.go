@@ -0,0 +1,338 @@
+package sql
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/beatlabs/patron/trace"
+	"github.com/lib/pq"
+)
+
+// defaultMySQLBatchSize is how many rows CopyFromOptions batches into a
+// single multi-row INSERT against a mysql DB, absent WithBatchSize.
+const defaultMySQLBatchSize = 500
+
+// RowSource iterates the rows DB.CopyFrom writes, one at a time, the same
+// shape as database/sql.Rows: call Next until it returns false, reading
+// Row in between, then check Err for anything that stopped iteration
+// early.
+type RowSource interface {
+	// Next advances to the next row, reporting whether one is available.
+	Next() bool
+	// Row returns the current row's column values, in the same order as
+	// CopyFrom's columns argument. Valid only until the next call to Next.
+	Row() []interface{}
+	// Err returns the first error Next encountered, if iteration stopped
+	// before exhausting the source.
+	Err() error
+}
+
+// CSVRowSource is a RowSource that reads rows, as columns of raw strings,
+// from a csv.Reader, so a CSV file can be piped straight into DB.CopyFrom.
+// r is expected to already have had any header row consumed.
+type CSVRowSource struct {
+	r   *csv.Reader
+	row []interface{}
+	err error
+}
+
+// NewCSVRowSource creates a RowSource that reads records from r.
+func NewCSVRowSource(r *csv.Reader) *CSVRowSource {
+	return &CSVRowSource{r: r}
+}
+
+// Next implements RowSource.
+func (s *CSVRowSource) Next() bool {
+	record, err := s.r.Read()
+	if err == io.EOF {
+		return false
+	}
+	if err != nil {
+		s.err = fmt.Errorf("sql: failed to read CSV record: %w", err)
+		return false
+	}
+
+	row := make([]interface{}, len(record))
+	for i, v := range record {
+		row[i] = v
+	}
+	s.row = row
+	return true
+}
+
+// Row implements RowSource.
+func (s *CSVRowSource) Row() []interface{} {
+	return s.row
+}
+
+// Err implements RowSource.
+func (s *CSVRowSource) Err() error {
+	return s.err
+}
+
+// CopyFromOptionFunc defines an option function for DB.CopyFrom.
+type CopyFromOptionFunc func(*copyFromOptions) error
+
+type copyFromOptions struct {
+	mysqlBatchSize int
+}
+
+// WithBatchSize overrides how many rows CopyFrom batches into a single
+// multi-row INSERT when db's driver is mysql. It has no effect against
+// postgres, which streams every row through a single COPY statement, or
+// other drivers, which insert one row at a time inside a single
+// transaction. It defaults to 500.
+func WithBatchSize(n int) CopyFromOptionFunc {
+	return func(o *copyFromOptions) error {
+		if n <= 0 {
+			return errors.New("sql: batch size must be greater than 0")
+		}
+		o.mysqlBatchSize = n
+		return nil
+	}
+}
+
+// CopyFrom bulk-loads every row src yields into table's columns, the way
+// db's driver does it fastest: against postgres (detected via DriverName,
+// same set recognized by bindTypeForDriver/migrate's lockerFor) it streams
+// rows through a single COPY FROM STDIN statement inside a transaction;
+// against mysql it batches rows into multi-row INSERTs, sized via
+// WithBatchSize; any other driver falls back to a prepared single-row
+// INSERT, re-executed inside one transaction for every row. Either way, it
+// returns the number of rows written and produces one span, tagged with
+// the row count and an estimate of the bytes written.
+func (db *DB) CopyFrom(ctx context.Context, table string, columns []string, src RowSource, oo ...CopyFromOptionFunc) (int64, error) {
+	opts := copyFromOptions{mysqlBatchSize: defaultMySQLBatchSize}
+	for _, o := range oo {
+		if err := o(&opts); err != nil {
+			return 0, err
+		}
+	}
+
+	stmt := fmt.Sprintf("COPY %s(%s)", table, strings.Join(columns, ", "))
+	sp, _ := db.startSpan(ctx, "db.CopyFrom", stmt)
+
+	var rows, bytesWritten int64
+	var err error
+	switch db.driver {
+	case "postgres", "pgx", "pq", "cloudsqlpostgres":
+		rows, bytesWritten, err = db.copyFromPostgres(ctx, table, columns, src)
+	case "mysql":
+		rows, bytesWritten, err = db.copyFromMySQLBatches(ctx, table, columns, src, opts.mysqlBatchSize)
+	default:
+		rows, bytesWritten, err = db.copyFromPreparedLoop(ctx, table, columns, src)
+	}
+
+	sp.SetTag("db.rows_affected", rows)
+	sp.SetTag("db.bytes_written", bytesWritten)
+	trace.SpanComplete(sp, err)
+	return rows, err
+}
+
+// copyFromPostgres streams src through a single COPY FROM STDIN statement,
+// prepared via lib/pq's CopyIn, inside its own transaction.
+func (db *DB) copyFromPostgres(ctx context.Context, table string, columns []string, src RowSource) (int64, int64, error) {
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, 0, fmt.Errorf("sql: failed to begin copy transaction: %w", err)
+	}
+
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn(table, columns...))
+	if err != nil {
+		_ = tx.Rollback()
+		return 0, 0, fmt.Errorf("sql: failed to prepare copy statement: %w", err)
+	}
+
+	var rows, bytesWritten int64
+	for src.Next() {
+		row := src.Row()
+		if _, err := stmt.ExecContext(ctx, row...); err != nil {
+			_ = stmt.Close()
+			_ = tx.Rollback()
+			return rows, bytesWritten, fmt.Errorf("sql: failed to copy row: %w", err)
+		}
+		rows++
+		bytesWritten += rowBytes(row)
+	}
+	if err := src.Err(); err != nil {
+		_ = stmt.Close()
+		_ = tx.Rollback()
+		return rows, bytesWritten, fmt.Errorf("sql: row source failed: %w", err)
+	}
+
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		_ = stmt.Close()
+		_ = tx.Rollback()
+		return rows, bytesWritten, fmt.Errorf("sql: failed to flush copy: %w", err)
+	}
+	if err := stmt.Close(); err != nil {
+		_ = tx.Rollback()
+		return rows, bytesWritten, fmt.Errorf("sql: failed to close copy statement: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return rows, bytesWritten, fmt.Errorf("sql: failed to commit copy transaction: %w", err)
+	}
+	return rows, bytesWritten, nil
+}
+
+// copyFromMySQLBatches groups src into multi-row INSERTs of up to
+// batchSize rows each, all inside a single transaction, since mysql has no
+// COPY equivalent.
+func (db *DB) copyFromMySQLBatches(ctx context.Context, table string, columns []string, src RowSource, batchSize int) (int64, int64, error) {
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, 0, fmt.Errorf("sql: failed to begin copy transaction: %w", err)
+	}
+
+	var rows, bytesWritten int64
+	batch := make([][]interface{}, 0, batchSize)
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		query, args := multiRowInsert(table, columns, batch)
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return fmt.Errorf("sql: failed to insert batch: %w", err)
+		}
+		batch = batch[:0]
+		return nil
+	}
+
+	for src.Next() {
+		row := src.Row()
+		batch = append(batch, row)
+		bytesWritten += rowBytes(row)
+		rows++
+
+		if len(batch) >= batchSize {
+			if err := flush(); err != nil {
+				_ = tx.Rollback()
+				return rows, bytesWritten, err
+			}
+		}
+	}
+	if err := src.Err(); err != nil {
+		_ = tx.Rollback()
+		return rows, bytesWritten, fmt.Errorf("sql: row source failed: %w", err)
+	}
+	if err := flush(); err != nil {
+		_ = tx.Rollback()
+		return rows, bytesWritten, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return rows, bytesWritten, fmt.Errorf("sql: failed to commit copy transaction: %w", err)
+	}
+	return rows, bytesWritten, nil
+}
+
+// copyFromPreparedLoop inserts src one row at a time through a single
+// prepared statement, re-executed inside one transaction for the whole
+// operation. It is the fallback for drivers such as sqlite3 with neither a
+// COPY primitive nor a practical multi-row INSERT syntax.
+func (db *DB) copyFromPreparedLoop(ctx context.Context, table string, columns []string, src RowSource) (int64, int64, error) {
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, 0, fmt.Errorf("sql: failed to begin copy transaction: %w", err)
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s(%s) VALUES (%s)", table, strings.Join(columns, ", "),
+		placeholders(bindTypeForDriver(db.driver), len(columns), 1))
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		_ = tx.Rollback()
+		return 0, 0, fmt.Errorf("sql: failed to prepare insert statement: %w", err)
+	}
+
+	var rows, bytesWritten int64
+	for src.Next() {
+		row := src.Row()
+		if _, err := stmt.ExecContext(ctx, row...); err != nil {
+			_ = stmt.Close()
+			_ = tx.Rollback()
+			return rows, bytesWritten, fmt.Errorf("sql: failed to insert row: %w", err)
+		}
+		rows++
+		bytesWritten += rowBytes(row)
+	}
+	if err := src.Err(); err != nil {
+		_ = stmt.Close()
+		_ = tx.Rollback()
+		return rows, bytesWritten, fmt.Errorf("sql: row source failed: %w", err)
+	}
+
+	if err := stmt.Close(); err != nil {
+		_ = tx.Rollback()
+		return rows, bytesWritten, fmt.Errorf("sql: failed to close insert statement: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return rows, bytesWritten, fmt.Errorf("sql: failed to commit copy transaction: %w", err)
+	}
+	return rows, bytesWritten, nil
+}
+
+// multiRowInsert builds a single "INSERT INTO table(cols) VALUES (...),
+// (...)" statement covering every row in batch, in mysql's "?" bindvar
+// style, plus its flattened positional arguments.
+func multiRowInsert(table string, columns []string, batch [][]interface{}) (string, []interface{}) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "INSERT INTO %s(%s) VALUES ", table, strings.Join(columns, ", "))
+
+	args := make([]interface{}, 0, len(batch)*len(columns))
+	rowPlaceholders := placeholders(bindQuestion, len(columns), 1)
+	for i, row := range batch {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteByte('(')
+		b.WriteString(rowPlaceholders)
+		b.WriteByte(')')
+		args = append(args, row...)
+	}
+
+	return b.String(), args
+}
+
+// placeholders returns n comma-separated positional bindvars in bt's
+// style, numbered from offset where the style requires numbering.
+func placeholders(bt bindType, n, offset int) string {
+	ps := make([]string, n)
+	for i := 0; i < n; i++ {
+		switch bt {
+		case bindDollar:
+			ps[i] = "$" + strconv.Itoa(offset+i)
+		case bindColon:
+			ps[i] = ":" + strconv.Itoa(offset+i)
+		case bindAt:
+			ps[i] = "@p" + strconv.Itoa(offset+i)
+		default:
+			ps[i] = "?"
+		}
+	}
+	return strings.Join(ps, ", ")
+}
+
+// rowBytes estimates the wire size of row's values, for CopyFrom's
+// db.bytes_written span tag. It is a byte-length approximation of each
+// value's textual form, not the exact number of bytes the driver sends.
+func rowBytes(row []interface{}) int64 {
+	var n int64
+	for _, v := range row {
+		switch val := v.(type) {
+		case []byte:
+			n += int64(len(val))
+		case string:
+			n += int64(len(val))
+		case nil:
+		default:
+			n += int64(len(fmt.Sprint(val)))
+		}
+	}
+	return n
+}
@@ -0,0 +1,93 @@
+package sql
+
+import (
+	"encoding/csv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCSVRowSource(t *testing.T) {
+	src := NewCSVRowSource(csv.NewReader(strings.NewReader("1,alice\n2,bob\n")))
+
+	require.True(t, src.Next())
+	assert.Equal(t, []interface{}{"1", "alice"}, src.Row())
+
+	require.True(t, src.Next())
+	assert.Equal(t, []interface{}{"2", "bob"}, src.Row())
+
+	assert.False(t, src.Next())
+	assert.NoError(t, src.Err())
+}
+
+func TestCSVRowSource_MalformedRecord(t *testing.T) {
+	src := NewCSVRowSource(csv.NewReader(strings.NewReader("1,alice\n2,\"unterminated\n")))
+
+	require.True(t, src.Next())
+	assert.False(t, src.Next())
+	assert.Error(t, src.Err())
+}
+
+func TestWithBatchSize_Invalid(t *testing.T) {
+	err := WithBatchSize(0)(&copyFromOptions{})
+	assert.Error(t, err)
+}
+
+func TestWithBatchSize(t *testing.T) {
+	opts := copyFromOptions{}
+	require.NoError(t, WithBatchSize(100)(&opts))
+	assert.Equal(t, 100, opts.mysqlBatchSize)
+}
+
+func TestPlaceholders(t *testing.T) {
+	type testcase struct {
+		bt     bindType
+		n      int
+		offset int
+		want   string
+	}
+
+	var testcases = []testcase{
+		{bindQuestion, 3, 1, "?, ?, ?"},
+		{bindDollar, 3, 1, "$1, $2, $3"},
+		{bindDollar, 2, 4, "$4, $5"},
+		{bindColon, 2, 1, ":1, :2"},
+		{bindAt, 2, 1, "@p1, @p2"},
+	}
+
+	for _, tc := range testcases {
+		assert.Equal(t, tc.want, placeholders(tc.bt, tc.n, tc.offset))
+	}
+}
+
+func TestMultiRowInsert(t *testing.T) {
+	batch := [][]interface{}{
+		{1, "alice"},
+		{2, "bob"},
+	}
+
+	query, args := multiRowInsert("users", []string{"id", "name"}, batch)
+
+	assert.Equal(t, "INSERT INTO users(id, name) VALUES (?, ?), (?, ?)", query)
+	assert.Equal(t, []interface{}{1, "alice", 2, "bob"}, args)
+}
+
+func TestRowBytes(t *testing.T) {
+	type testcase struct {
+		row  []interface{}
+		want int64
+	}
+
+	var testcases = []testcase{
+		{[]interface{}{"abc", 123}, 6},
+		{[]interface{}{[]byte("abcd")}, 4},
+		{[]interface{}{nil, "ab"}, 2},
+		{nil, 0},
+	}
+
+	for _, tc := range testcases {
+		assert.Equal(t, tc.want, rowBytes(tc.row))
+	}
+}
@@ -0,0 +1,160 @@
+package sql
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// fieldMap maps a lowercased "db" tag (or, absent one, lowercased field
+// name) to the reflect.Value.FieldByIndex path of the struct field it binds
+// to. Anonymous (embedded) struct fields are flattened into their parent's
+// map, one level at a time, so an embedded struct's columns are addressable
+// exactly like the outer struct's own fields.
+type fieldMap map[string][]int
+
+// mapperCache caches the fieldMap for a struct type, mirroring how a
+// sqlx.Mapper amortizes the reflection cost of repeated Get/Select calls
+// against the same destination type.
+var mapperCache sync.Map // map[reflect.Type]fieldMap
+
+// fieldMapFor returns the cached fieldMap for t, building and caching it on
+// first use.
+func fieldMapFor(t reflect.Type) fieldMap {
+	if v, ok := mapperCache.Load(t); ok {
+		return v.(fieldMap)
+	}
+
+	fm := make(fieldMap)
+	buildFieldMap(t, nil, fm)
+	mapperCache.Store(t, fm)
+	return fm
+}
+
+// buildFieldMap walks t's fields, recording each into fm under its "db" tag
+// (or lowercased name), prefixed by path. It recurses into anonymous struct
+// fields so their columns are exposed as if they belonged to t directly. An
+// outer field never loses to a same-named embedded one, matching normal Go
+// shadowing rules.
+func buildFieldMap(t reflect.Type, path []int, fm fieldMap) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue // unexported
+		}
+
+		idx := append(append([]int(nil), path...), i)
+
+		if f.Anonymous {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				buildFieldMap(ft, idx, fm)
+				continue
+			}
+		}
+
+		name := f.Tag.Get("db")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		if existing, exists := fm[name]; !exists || len(idx) < len(existing) {
+			fm[name] = idx
+		}
+	}
+}
+
+// scanArgsFor builds the []interface{} rows.Scan destination for cols
+// against the struct value v, using fm to find each column's field; columns
+// with no matching field are discarded into a throwaway sink rather than
+// erroring, so a Get/Select query is free to select columns dest doesn't
+// care about.
+func scanArgsFor(v reflect.Value, fm fieldMap, cols []string) []interface{} {
+	args := make([]interface{}, len(cols))
+	for i, col := range cols {
+		idx, ok := fm[strings.ToLower(col)]
+		if !ok {
+			var discard interface{}
+			args[i] = &discard
+			continue
+		}
+		args[i] = v.FieldByIndex(idx).Addr().Interface()
+	}
+	return args
+}
+
+// scanRowInto scans the current row of rows into dest, a pointer to either
+// a struct (matched by column name via fieldMapFor) or a scalar.
+func scanRowInto(rows *sql.Rows, dest interface{}) error {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return fmt.Errorf("sql: Get destination must be a non-nil pointer, got %T", dest)
+	}
+
+	elem := v.Elem()
+	if elem.Kind() != reflect.Struct {
+		return rows.Scan(dest)
+	}
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	return rows.Scan(scanArgsFor(elem, fieldMapFor(elem.Type()), cols)...)
+}
+
+// scanRowsInto appends every remaining row of rows onto dest, a pointer to
+// a slice of structs (matched by column name) or scalars.
+func scanRowsInto(rows *sql.Rows, dest interface{}) error {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("sql: Select destination must be a pointer to a slice, got %T", dest)
+	}
+
+	slice := v.Elem()
+	elemType := slice.Type().Elem()
+	isPtrElem := elemType.Kind() == reflect.Ptr
+	structType := elemType
+	if isPtrElem {
+		structType = elemType.Elem()
+	}
+	isStruct := structType.Kind() == reflect.Struct
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	var fm fieldMap
+	if isStruct {
+		fm = fieldMapFor(structType)
+	}
+
+	for rows.Next() {
+		row := reflect.New(structType)
+		if isStruct {
+			err = rows.Scan(scanArgsFor(row.Elem(), fm, cols)...)
+		} else {
+			err = rows.Scan(row.Interface())
+		}
+		if err != nil {
+			return err
+		}
+
+		if isPtrElem {
+			slice.Set(reflect.Append(slice, row))
+		} else {
+			slice.Set(reflect.Append(slice, row.Elem()))
+		}
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,69 @@
+package sql
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type mapperTestUser struct {
+	ID   int `db:"id"`
+	Name string
+	Tag  struct {
+		Ignored string
+	} `db:"-"`
+	unexported string // nolint
+}
+
+type mapperTestEmployee struct {
+	mapperTestUser
+	Role string
+}
+
+func TestFieldMapFor(t *testing.T) {
+	fm := fieldMapFor(reflect.TypeOf(mapperTestUser{}))
+	assert.Equal(t, []int{0}, []int(fm["id"]))
+	assert.Equal(t, []int{1}, []int(fm["name"]))
+	_, ok := fm["tag"]
+	assert.False(t, ok)
+	_, ok = fm["unexported"]
+	assert.False(t, ok)
+}
+
+func TestFieldMapForEmbedded(t *testing.T) {
+	fm := fieldMapFor(reflect.TypeOf(mapperTestEmployee{}))
+	assert.Equal(t, []int{0, 0}, []int(fm["id"]))
+	assert.Equal(t, []int{0, 1}, []int(fm["name"]))
+	assert.Equal(t, []int{1}, []int(fm["role"]))
+}
+
+func TestBindNamedArgsFromMap(t *testing.T) {
+	args, err := bindNamedArgs(map[string]interface{}{"id": 1, "name": "bob"}, []string{"name", "id"})
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"bob", 1}, args)
+}
+
+func TestBindNamedArgsFromMapMissing(t *testing.T) {
+	_, err := bindNamedArgs(map[string]interface{}{"id": 1}, []string{"name"})
+	assert.Error(t, err)
+}
+
+func TestBindNamedArgsFromStruct(t *testing.T) {
+	u := mapperTestUser{ID: 42, Name: "alice"}
+	args, err := bindNamedArgs(u, []string{"id", "name"})
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{42, "alice"}, args)
+}
+
+func TestBindNamedArgsFromStructPointer(t *testing.T) {
+	u := &mapperTestUser{ID: 42, Name: "alice"}
+	args, err := bindNamedArgs(u, []string{"name"})
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"alice"}, args)
+}
+
+func TestBindNamedArgsUnsupportedType(t *testing.T) {
+	_, err := bindNamedArgs(42, []string{"id"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,124 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	sqltrace "github.com/beatlabs/patron/trace/sql"
+)
+
+// advisoryLockID is the fixed lock key every Migrator in this process
+// takes before running a migration step, scoped to a single database by
+// virtue of the lock being session/connection-local (postgres, mysql) or
+// row-local within schema_migrations (the generic fallback).
+const advisoryLockID = 8517190925
+
+// lockName is the mysql named-lock equivalent of advisoryLockID; GET_LOCK
+// takes a string, not an integer key.
+const lockName = "patron_schema_migrations"
+
+// locker guards a migration run against concurrent replicas of the same
+// service racing to apply the same migration twice.
+type locker interface {
+	lock(ctx context.Context, db *sqltrace.DB) (unlock func(context.Context) error, err error)
+}
+
+// lockerFor picks the locking strategy for driverName, falling back to
+// txLocker for drivers with no native advisory-lock primitive.
+func lockerFor(driverName, lockTimeout string) locker {
+	switch driverName {
+	case "postgres", "pgx", "pq", "cloudsqlpostgres":
+		return pgLocker{}
+	case "mysql":
+		return mysqlLocker{timeout: lockTimeout}
+	default:
+		return txLocker{}
+	}
+}
+
+// pgLocker uses pg_advisory_lock/pg_advisory_unlock, held for the
+// lifetime of a single *sql.Conn so the lock and unlock run on the same
+// backend session.
+type pgLocker struct{}
+
+func (pgLocker) lock(ctx context.Context, db *sqltrace.DB) (func(context.Context) error, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", advisoryLockID); err != nil {
+		_ = conn.Close(ctx)
+		return nil, fmt.Errorf("migrate: failed to acquire postgres advisory lock: %w", err)
+	}
+
+	return func(ctx context.Context) error {
+		_, unlockErr := conn.Exec(ctx, "SELECT pg_advisory_unlock($1)", advisoryLockID)
+		closeErr := conn.Close(ctx)
+		if unlockErr != nil {
+			return fmt.Errorf("migrate: failed to release postgres advisory lock: %w", unlockErr)
+		}
+		return closeErr
+	}, nil
+}
+
+// mysqlLocker uses GET_LOCK/RELEASE_LOCK, held for the lifetime of a
+// single *sql.Conn for the same session-scoping reason as pgLocker.
+type mysqlLocker struct {
+	timeout string
+}
+
+func (l mysqlLocker) lock(ctx context.Context, db *sqltrace.DB) (func(context.Context) error, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := l.timeout
+	if timeout == "" {
+		timeout = "10"
+	}
+
+	var acquired int
+	row := conn.QueryRow(ctx, "SELECT GET_LOCK(?, ?)", lockName, timeout)
+	if err := row.Scan(&acquired); err != nil {
+		_ = conn.Close(ctx)
+		return nil, fmt.Errorf("migrate: failed to acquire mysql lock: %w", err)
+	}
+	if acquired != 1 {
+		_ = conn.Close(ctx)
+		return nil, errors.New("migrate: mysql lock is held by another session")
+	}
+
+	return func(ctx context.Context) error {
+		_, unlockErr := conn.Exec(ctx, "SELECT RELEASE_LOCK(?)", lockName)
+		closeErr := conn.Close(ctx)
+		if unlockErr != nil {
+			return fmt.Errorf("migrate: failed to release mysql lock: %w", unlockErr)
+		}
+		return closeErr
+	}, nil
+}
+
+// txLocker is the fallback for drivers with no native advisory-lock
+// primitive: it opens a transaction and SELECT ... FOR UPDATEs the
+// schema_migrations sentinel row (version 0, created by ensureSchema),
+// holding that row lock - and the transaction - open for the whole run.
+type txLocker struct{}
+
+func (txLocker) lock(ctx context.Context, db *sqltrace.DB) (func(context.Context) error, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(ctx, "SELECT version FROM schema_migrations WHERE version = 0 FOR UPDATE"); err != nil {
+		_ = tx.Rollback(ctx)
+		return nil, fmt.Errorf("migrate: failed to acquire schema_migrations lock row: %w", err)
+	}
+
+	return func(context.Context) error {
+		return tx.Commit(ctx)
+	}, nil
+}
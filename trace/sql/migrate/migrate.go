@@ -0,0 +1,343 @@
+// Package migrate applies versioned SQL schema migrations through this
+// module's traced sql package, so migration execution shows up in the
+// same traces as the rest of a service's database activity.
+//
+// Migrations are modelled on the goose/migrate style: each one is a
+// ".sql" file named "<version>_<name>.sql" containing an up and a down
+// statement pair separated by "-- +migrate Up" / "-- +migrate Down"
+// markers, loaded from a directory on disk (DirSource) or an embedded
+// fs.FS (FSSource).
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	sqltrace "github.com/beatlabs/patron/trace/sql"
+)
+
+// Migration is a single versioned schema change.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Source provides the set of migrations a Migrator applies.
+type Source interface {
+	Migrations() ([]Migration, error)
+}
+
+// StatusEntry reports a single migration's applied state.
+type StatusEntry struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	Dirty     bool
+	AppliedAt time.Time
+}
+
+// schemaTable is intentionally driver-agnostic SQL: it avoids
+// autoincrement/serial syntax, since version is always supplied by the
+// migration file name, never generated.
+const schemaTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version BIGINT PRIMARY KEY,
+	dirty BOOL NOT NULL DEFAULT false,
+	applied_at TIMESTAMP
+)`
+
+// lockRowSentinel is inserted once, at version 0, purely so txLocker has
+// a row to SELECT ... FOR UPDATE on drivers without a native advisory
+// lock. It is excluded from Status and never touched by Up/Down/StepTo.
+const lockRowSentinel = `INSERT INTO schema_migrations (version, dirty)
+	SELECT 0, false
+	WHERE NOT EXISTS (SELECT 1 FROM schema_migrations WHERE version = 0)`
+
+// Migrator applies a Source's migrations against a *sql.DB's schema,
+// guarded by a driver-appropriate lock so concurrent replicas of the
+// same service don't race to apply the same migration twice.
+type Migrator struct {
+	db          *sqltrace.DB
+	source      Source
+	lockTimeout string
+	locker      locker
+}
+
+// MigratorOptionFunc defines an option function for New.
+type MigratorOptionFunc func(*Migrator) error
+
+// WithLockTimeout sets how long the mysql locker waits for GET_LOCK
+// before giving up, in seconds. It has no effect on the postgres locker,
+// which blocks on pg_advisory_lock until acquired, or on the generic
+// fallback, whose row lock waits for the database's own lock_timeout.
+func WithLockTimeout(seconds int) MigratorOptionFunc {
+	return func(m *Migrator) error {
+		if seconds <= 0 {
+			return errors.New("lock timeout must be greater than 0")
+		}
+		m.lockTimeout = fmt.Sprintf("%d", seconds)
+		return nil
+	}
+}
+
+// New returns a Migrator that applies source's migrations against db.
+func New(db *sqltrace.DB, source Source, oo ...MigratorOptionFunc) (*Migrator, error) {
+	if db == nil {
+		return nil, errors.New("db is required")
+	}
+	if source == nil {
+		return nil, errors.New("source is required")
+	}
+
+	m := &Migrator{
+		db:     db,
+		source: source,
+	}
+
+	for _, o := range oo {
+		if err := o(m); err != nil {
+			return nil, err
+		}
+	}
+
+	m.locker = lockerFor(db.DriverName(), m.lockTimeout)
+
+	return m, nil
+}
+
+// Up applies every migration with a version greater than the highest
+// currently-applied one, in ascending order.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.withLock(ctx, func(ctx context.Context) error {
+		migrations, err := m.sortedMigrations()
+		if err != nil {
+			return err
+		}
+		applied, err := m.appliedVersions(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, mg := range migrations {
+			if applied[mg.Version].Applied {
+				continue
+			}
+			if err := m.applyUp(ctx, mg); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Down reverts the single most-recently-applied migration. Call it
+// repeatedly to step back further.
+func (m *Migrator) Down(ctx context.Context) error {
+	return m.withLock(ctx, func(ctx context.Context) error {
+		migrations, err := m.sortedMigrations()
+		if err != nil {
+			return err
+		}
+		applied, err := m.appliedVersions(ctx)
+		if err != nil {
+			return err
+		}
+
+		var last *Migration
+		for i := range migrations {
+			if applied[migrations[i].Version].Applied {
+				last = &migrations[i]
+			}
+		}
+		if last == nil {
+			return nil
+		}
+
+		return m.applyDown(ctx, *last)
+	})
+}
+
+// StepTo migrates up or down until exactly the migrations with version
+// <= target are applied.
+func (m *Migrator) StepTo(ctx context.Context, target int64) error {
+	return m.withLock(ctx, func(ctx context.Context) error {
+		migrations, err := m.sortedMigrations()
+		if err != nil {
+			return err
+		}
+		applied, err := m.appliedVersions(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, mg := range migrations {
+			if mg.Version <= target && !applied[mg.Version].Applied {
+				if err := m.applyUp(ctx, mg); err != nil {
+					return err
+				}
+			}
+		}
+
+		for i := len(migrations) - 1; i >= 0; i-- {
+			mg := migrations[i]
+			if mg.Version > target && applied[mg.Version].Applied {
+				if err := m.applyDown(ctx, mg); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+// Status reports every migration in source alongside whether, and when,
+// it has been applied.
+func (m *Migrator) Status(ctx context.Context) ([]StatusEntry, error) {
+	if err := m.ensureSchema(ctx); err != nil {
+		return nil, err
+	}
+
+	migrations, err := m.sortedMigrations()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := m.appliedVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]StatusEntry, 0, len(migrations))
+	for _, mg := range migrations {
+		e := applied[mg.Version]
+		e.Version = mg.Version
+		e.Name = mg.Name
+		statuses = append(statuses, e)
+	}
+	return statuses, nil
+}
+
+func (m *Migrator) withLock(ctx context.Context, f func(ctx context.Context) error) (err error) {
+	if err = m.ensureSchema(ctx); err != nil {
+		return err
+	}
+
+	unlock, err := m.locker.lock(ctx, m.db)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if uerr := unlock(ctx); uerr != nil && err == nil {
+			err = uerr
+		}
+	}()
+
+	err = f(ctx)
+	return err
+}
+
+func (m *Migrator) ensureSchema(ctx context.Context) error {
+	if _, err := m.db.Exec(ctx, schemaTable); err != nil {
+		return fmt.Errorf("migrate: failed to create schema_migrations table: %w", err)
+	}
+	if _, err := m.db.Exec(ctx, lockRowSentinel); err != nil {
+		return fmt.Errorf("migrate: failed to seed schema_migrations lock row: %w", err)
+	}
+	return nil
+}
+
+func (m *Migrator) appliedVersions(ctx context.Context) (map[int64]StatusEntry, error) {
+	rows, err := m.db.Query(ctx, "SELECT version, dirty, applied_at FROM schema_migrations WHERE version <> 0")
+	if err != nil {
+		return nil, fmt.Errorf("migrate: failed to read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]StatusEntry)
+	for rows.Next() {
+		var e StatusEntry
+		if err := rows.Scan(&e.Version, &e.Dirty, &e.AppliedAt); err != nil {
+			return nil, fmt.Errorf("migrate: failed to scan schema_migrations row: %w", err)
+		}
+		e.Applied = true
+		applied[e.Version] = e
+	}
+	return applied, rows.Err()
+}
+
+func (m *Migrator) applyUp(ctx context.Context, mg Migration) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	args := map[string]interface{}{"version": mg.Version, "applied_at": time.Now().UTC()}
+
+	if _, err := tx.NamedExec(ctx, "INSERT INTO schema_migrations (version, dirty, applied_at) VALUES (:version, true, :applied_at)", args); err != nil {
+		_ = tx.Rollback(ctx)
+		return fmt.Errorf("migrate: failed to record migration %d as dirty: %w", mg.Version, err)
+	}
+
+	if _, err := tx.Exec(ctx, mg.Up); err != nil {
+		_ = tx.Rollback(ctx)
+		return fmt.Errorf("migrate: failed to apply migration %d (%s): %w", mg.Version, mg.Name, err)
+	}
+
+	if _, err := tx.NamedExec(ctx, "UPDATE schema_migrations SET dirty = false WHERE version = :version", args); err != nil {
+		_ = tx.Rollback(ctx)
+		return fmt.Errorf("migrate: failed to clear dirty flag for migration %d: %w", mg.Version, err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (m *Migrator) applyDown(ctx context.Context, mg Migration) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	args := map[string]interface{}{"version": mg.Version}
+
+	if _, err := tx.NamedExec(ctx, "UPDATE schema_migrations SET dirty = true WHERE version = :version", args); err != nil {
+		_ = tx.Rollback(ctx)
+		return fmt.Errorf("migrate: failed to mark migration %d dirty: %w", mg.Version, err)
+	}
+
+	if _, err := tx.Exec(ctx, mg.Down); err != nil {
+		_ = tx.Rollback(ctx)
+		return fmt.Errorf("migrate: failed to revert migration %d (%s): %w", mg.Version, mg.Name, err)
+	}
+
+	if _, err := tx.NamedExec(ctx, "DELETE FROM schema_migrations WHERE version = :version", args); err != nil {
+		_ = tx.Rollback(ctx)
+		return fmt.Errorf("migrate: failed to remove migration %d record: %w", mg.Version, err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (m *Migrator) sortedMigrations() ([]Migration, error) {
+	migrations, err := m.source.Migrations()
+	if err != nil {
+		return nil, fmt.Errorf("migrate: failed to load migrations: %w", err)
+	}
+
+	sorted := make([]Migration, len(migrations))
+	copy(sorted, migrations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+
+	seen := make(map[int64]bool, len(sorted))
+	for _, mg := range sorted {
+		if seen[mg.Version] {
+			return nil, fmt.Errorf("migrate: duplicate migration version %d", mg.Version)
+		}
+		seen[mg.Version] = true
+	}
+
+	return sorted, nil
+}
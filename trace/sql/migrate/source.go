@@ -0,0 +1,146 @@
+package migrate
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// migrationFileName matches "<version>_<name>.sql", e.g. "0001_init.sql"
+// or "20060102150405_add_users.sql".
+var migrationFileName = regexp.MustCompile(`^(\d+)_(.+)\.sql$`)
+
+const (
+	upMarker   = "-- +migrate Up"
+	downMarker = "-- +migrate Down"
+)
+
+// DirSource loads migrations from "<version>_<name>.sql" files in a
+// directory on disk.
+type DirSource struct {
+	dir string
+}
+
+// NewDirSource returns a Source that reads every migration file in dir.
+func NewDirSource(dir string) DirSource {
+	return DirSource{dir: dir}
+}
+
+// Migrations implements Source.
+func (s DirSource) Migrations() ([]Migration, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("migrate: failed to read migrations directory %s: %w", s.dir, err)
+	}
+
+	var migrations []Migration
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		content, err := os.ReadFile(filepath.Join(s.dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("migrate: failed to read migration file %s: %w", entry.Name(), err)
+		}
+
+		mg, ok, err := parseMigrationFile(entry.Name(), content)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			migrations = append(migrations, mg)
+		}
+	}
+
+	return migrations, nil
+}
+
+// FSSource loads migrations from "<version>_<name>.sql" files under dir
+// in an fs.FS, e.g. one built with go:embed.
+type FSSource struct {
+	fsys fs.FS
+	dir  string
+}
+
+// NewFSSource returns a Source that reads every migration file under dir
+// in fsys.
+func NewFSSource(fsys fs.FS, dir string) FSSource {
+	return FSSource{fsys: fsys, dir: dir}
+}
+
+// Migrations implements Source.
+func (s FSSource) Migrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(s.fsys, s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("migrate: failed to read migrations dir %s: %w", s.dir, err)
+	}
+
+	var migrations []Migration
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		content, err := fs.ReadFile(s.fsys, path.Join(s.dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("migrate: failed to read migration file %s: %w", entry.Name(), err)
+		}
+
+		mg, ok, err := parseMigrationFile(entry.Name(), content)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			migrations = append(migrations, mg)
+		}
+	}
+
+	return migrations, nil
+}
+
+// parseMigrationFile parses name/content into a Migration. Files not
+// matching migrationFileName are skipped (ok is false) rather than
+// erroring, so a migrations directory can hold a README or similar
+// alongside its .sql files.
+func parseMigrationFile(name string, content []byte) (Migration, bool, error) {
+	m := migrationFileName.FindStringSubmatch(name)
+	if m == nil {
+		return Migration{}, false, nil
+	}
+
+	version, err := strconv.ParseInt(m[1], 10, 64)
+	if err != nil {
+		return Migration{}, false, fmt.Errorf("migrate: invalid version in migration file %s: %w", name, err)
+	}
+
+	up, down, err := splitUpDown(string(content))
+	if err != nil {
+		return Migration{}, false, fmt.Errorf("migrate: %s: %w", name, err)
+	}
+
+	return Migration{Version: version, Name: m[2], Up: up, Down: down}, true, nil
+}
+
+// splitUpDown splits a migration file's content on its "-- +migrate Up"
+// and "-- +migrate Down" markers.
+func splitUpDown(content string) (up, down string, err error) {
+	upIdx := strings.Index(content, upMarker)
+	downIdx := strings.Index(content, downMarker)
+	if upIdx == -1 || downIdx == -1 {
+		return "", "", fmt.Errorf("missing %q or %q marker", upMarker, downMarker)
+	}
+	if upIdx > downIdx {
+		return "", "", fmt.Errorf("%q must come before %q", upMarker, downMarker)
+	}
+
+	up = strings.TrimSpace(content[upIdx+len(upMarker) : downIdx])
+	down = strings.TrimSpace(content[downIdx+len(downMarker):])
+
+	return up, down, nil
+}
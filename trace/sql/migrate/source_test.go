@@ -0,0 +1,56 @@
+package migrate
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const sampleMigration = `-- +migrate Up
+CREATE TABLE users (id BIGINT PRIMARY KEY);
+
+-- +migrate Down
+DROP TABLE users;
+`
+
+func TestParseMigrationFile(t *testing.T) {
+	mg, ok, err := parseMigrationFile("0001_create_users.sql", []byte(sampleMigration))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, int64(1), mg.Version)
+	assert.Equal(t, "create_users", mg.Name)
+	assert.Equal(t, "CREATE TABLE users (id BIGINT PRIMARY KEY);", mg.Up)
+	assert.Equal(t, "DROP TABLE users;", mg.Down)
+}
+
+func TestParseMigrationFileIgnoresNonMatching(t *testing.T) {
+	_, ok, err := parseMigrationFile("README.md", []byte("hello"))
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestParseMigrationFileMissingMarkers(t *testing.T) {
+	_, _, err := parseMigrationFile("0001_broken.sql", []byte("CREATE TABLE foo();"))
+	assert.Error(t, err)
+}
+
+func TestParseMigrationFileInvertedMarkers(t *testing.T) {
+	content := "-- +migrate Down\nDROP TABLE foo;\n-- +migrate Up\nCREATE TABLE foo();"
+	_, _, err := parseMigrationFile("0001_inverted.sql", []byte(content))
+	assert.Error(t, err)
+}
+
+func TestFSSourceMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/0001_create_users.sql": {Data: []byte(sampleMigration)},
+		"migrations/0002_add_index.sql": {Data: []byte(
+			"-- +migrate Up\nCREATE INDEX idx ON users(id);\n-- +migrate Down\nDROP INDEX idx;\n")},
+		"migrations/README.md": {Data: []byte("not a migration")},
+	}
+
+	src := NewFSSource(fsys, "migrations")
+	migrations, err := src.Migrations()
+	assert.NoError(t, err)
+	assert.Len(t, migrations, 2)
+}
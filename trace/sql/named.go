@@ -0,0 +1,272 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/beatlabs/patron/trace"
+	"github.com/opentracing/opentracing-go"
+)
+
+// bindNamed rewrites query's ":name" placeholders for driverName's bindvar
+// style and resolves arg (a map[string]interface{} or struct) into the
+// positional argument list the rewritten query expects.
+func bindNamed(driverName, query string, arg interface{}) (string, []interface{}, error) {
+	q, names := compileNamed(query, bindTypeForDriver(driverName))
+	args, err := bindNamedArgs(arg, names)
+	if err != nil {
+		return "", nil, err
+	}
+	return q, args, nil
+}
+
+// bindNamedArgs resolves names, in order, against arg.
+func bindNamedArgs(arg interface{}, names []string) ([]interface{}, error) {
+	if m, ok := arg.(map[string]interface{}); ok {
+		args := make([]interface{}, len(names))
+		for i, name := range names {
+			v, ok := m[name]
+			if !ok {
+				return nil, fmt.Errorf("sql: named parameter %q not found in argument map", name)
+			}
+			args[i] = v
+		}
+		return args, nil
+	}
+
+	v := reflect.ValueOf(arg)
+	for v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("sql: named argument must be a map[string]interface{} or struct, got %T", arg)
+	}
+
+	fm := fieldMapFor(v.Type())
+	args := make([]interface{}, len(names))
+	for i, name := range names {
+		idx, ok := fm[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("sql: named parameter %q has no matching field in %s", name, v.Type())
+		}
+		args[i] = v.FieldByIndex(idx).Interface()
+	}
+	return args, nil
+}
+
+// getFirstRow runs the span-completing boilerplate shared by every Get:
+// run query, require exactly one row, and scan it into dest.
+func getFirstRow(sp opentracing.Span, rows *sql.Rows, dest interface{}) error {
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return sql.ErrNoRows
+	}
+
+	return scanRowInto(rows, dest)
+}
+
+// NamedExec executes a query whose ":name" placeholders are bound from
+// arg's map entries or struct fields, rewritten to db's driver's bindvar
+// style. The span's db.statement tag carries the original named query.
+func (db *DB) NamedExec(ctx context.Context, query string, arg interface{}) (sql.Result, error) {
+	sp, _ := db.startSpan(ctx, "db.NamedExec", query)
+	q, args, err := bindNamed(db.driver, query, arg)
+	if err != nil {
+		trace.SpanComplete(sp, err)
+		return nil, err
+	}
+	res, err := db.db.ExecContext(ctx, q, args...)
+	trace.SpanComplete(sp, err)
+	return res, err
+}
+
+// NamedQuery executes a row-returning query whose ":name" placeholders are
+// bound from arg's map entries or struct fields.
+func (db *DB) NamedQuery(ctx context.Context, query string, arg interface{}) (*sql.Rows, error) {
+	sp, _ := db.startSpan(ctx, "db.NamedQuery", query)
+	q, args, err := bindNamed(db.driver, query, arg)
+	if err != nil {
+		trace.SpanComplete(sp, err)
+		return nil, err
+	}
+	rows, err := db.db.QueryContext(ctx, q, args...)
+	trace.SpanComplete(sp, err)
+	return rows, err
+}
+
+// Get executes query with args and scans the single resulting row into
+// dest, a pointer to a struct (matched by "db" tag) or a scalar.
+func (db *DB) Get(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	sp, _ := db.startSpan(ctx, "db.Get", query)
+	rows, err := db.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		trace.SpanComplete(sp, err)
+		return err
+	}
+	err = getFirstRow(sp, rows, dest)
+	trace.SpanComplete(sp, err)
+	return err
+}
+
+// Select executes query with args and appends every resulting row onto
+// dest, a pointer to a slice of structs (matched by "db" tag) or scalars.
+func (db *DB) Select(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	sp, _ := db.startSpan(ctx, "db.Select", query)
+	rows, err := db.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		trace.SpanComplete(sp, err)
+		return err
+	}
+	defer rows.Close()
+	err = scanRowsInto(rows, dest)
+	trace.SpanComplete(sp, err)
+	return err
+}
+
+// NamedExec executes a query whose ":name" placeholders are bound from
+// arg's map entries or struct fields.
+func (c *Conn) NamedExec(ctx context.Context, query string, arg interface{}) (sql.Result, error) {
+	sp, _ := c.startSpan(ctx, "conn.NamedExec", query)
+	q, args, err := bindNamed(c.driver, query, arg)
+	if err != nil {
+		trace.SpanComplete(sp, err)
+		return nil, err
+	}
+	res, err := c.conn.ExecContext(ctx, q, args...)
+	trace.SpanComplete(sp, err)
+	return res, err
+}
+
+// NamedQuery executes a row-returning query whose ":name" placeholders are
+// bound from arg's map entries or struct fields.
+func (c *Conn) NamedQuery(ctx context.Context, query string, arg interface{}) (*sql.Rows, error) {
+	sp, _ := c.startSpan(ctx, "conn.NamedQuery", query)
+	q, args, err := bindNamed(c.driver, query, arg)
+	if err != nil {
+		trace.SpanComplete(sp, err)
+		return nil, err
+	}
+	rows, err := c.conn.QueryContext(ctx, q, args...)
+	trace.SpanComplete(sp, err)
+	return rows, err
+}
+
+// Get executes query with args and scans the single resulting row into dest.
+func (c *Conn) Get(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	sp, _ := c.startSpan(ctx, "conn.Get", query)
+	rows, err := c.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		trace.SpanComplete(sp, err)
+		return err
+	}
+	err = getFirstRow(sp, rows, dest)
+	trace.SpanComplete(sp, err)
+	return err
+}
+
+// Select executes query with args and appends every resulting row onto dest.
+func (c *Conn) Select(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	sp, _ := c.startSpan(ctx, "conn.Select", query)
+	rows, err := c.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		trace.SpanComplete(sp, err)
+		return err
+	}
+	defer rows.Close()
+	err = scanRowsInto(rows, dest)
+	trace.SpanComplete(sp, err)
+	return err
+}
+
+// NamedExec executes a query whose ":name" placeholders are bound from
+// arg's map entries or struct fields.
+func (tx *Tx) NamedExec(ctx context.Context, query string, arg interface{}) (sql.Result, error) {
+	sp, _ := tx.startSpan(ctx, "tx.NamedExec", query)
+	q, args, err := bindNamed(tx.driver, query, arg)
+	if err != nil {
+		trace.SpanComplete(sp, err)
+		return nil, err
+	}
+	res, err := tx.tx.ExecContext(ctx, q, args...)
+	trace.SpanComplete(sp, err)
+	return res, err
+}
+
+// NamedQuery executes a row-returning query whose ":name" placeholders are
+// bound from arg's map entries or struct fields.
+func (tx *Tx) NamedQuery(ctx context.Context, query string, arg interface{}) (*sql.Rows, error) {
+	sp, _ := tx.startSpan(ctx, "tx.NamedQuery", query)
+	q, args, err := bindNamed(tx.driver, query, arg)
+	if err != nil {
+		trace.SpanComplete(sp, err)
+		return nil, err
+	}
+	rows, err := tx.tx.QueryContext(ctx, q, args...)
+	trace.SpanComplete(sp, err)
+	return rows, err
+}
+
+// Get executes query with args and scans the single resulting row into dest.
+func (tx *Tx) Get(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	sp, _ := tx.startSpan(ctx, "tx.Get", query)
+	rows, err := tx.tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		trace.SpanComplete(sp, err)
+		return err
+	}
+	err = getFirstRow(sp, rows, dest)
+	trace.SpanComplete(sp, err)
+	return err
+}
+
+// Select executes query with args and appends every resulting row onto dest.
+func (tx *Tx) Select(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	sp, _ := tx.startSpan(ctx, "tx.Select", query)
+	rows, err := tx.tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		trace.SpanComplete(sp, err)
+		return err
+	}
+	defer rows.Close()
+	err = scanRowsInto(rows, dest)
+	trace.SpanComplete(sp, err)
+	return err
+}
+
+// Get executes the prepared statement with args and scans the single
+// resulting row into dest. A Stmt has no NamedExec/NamedQuery: it was
+// already prepared against positional placeholders, so there are no
+// ":name" tokens left to rewrite by the time one is available.
+func (s *Stmt) Get(ctx context.Context, dest interface{}, args ...interface{}) error {
+	sp, _ := s.startSpan(ctx, "stmt.Get", "")
+	rows, err := s.stmt.QueryContext(ctx, args...)
+	if err != nil {
+		trace.SpanComplete(sp, err)
+		return err
+	}
+	err = getFirstRow(sp, rows, dest)
+	trace.SpanComplete(sp, err)
+	return err
+}
+
+// Select executes the prepared statement with args and appends every
+// resulting row onto dest.
+func (s *Stmt) Select(ctx context.Context, dest interface{}, args ...interface{}) error {
+	sp, _ := s.startSpan(ctx, "stmt.Select", "")
+	rows, err := s.stmt.QueryContext(ctx, args...)
+	if err != nil {
+		trace.SpanComplete(sp, err)
+		return err
+	}
+	defer rows.Close()
+	err = scanRowsInto(rows, dest)
+	trace.SpanComplete(sp, err)
+	return err
+}
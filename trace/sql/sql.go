@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"database/sql/driver"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/beatlabs/patron/trace"
@@ -22,7 +23,7 @@ var (
 )
 
 type connInfo struct {
-	instance, user string
+	instance, user, driver string
 }
 
 func (c *connInfo) startSpan(ctx context.Context, opName, stmt string) (opentracing.Span, context.Context) {
@@ -54,7 +55,7 @@ func (c *Conn) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
 		return nil, err
 	}
 
-	return &Tx{tx: tx}, nil
+	return &Tx{tx: tx, connInfo: c.connInfo}, nil
 }
 
 // Close returns the connection to the connection pool.
@@ -89,7 +90,7 @@ func (c *Conn) Prepare(ctx context.Context, query string) (*Stmt, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &Stmt{stmt: stmt}, nil
+	return &Stmt{stmt: stmt, connInfo: c.connInfo}, nil
 }
 
 // Query executes a query that returns rows.
@@ -124,8 +125,12 @@ func Open(driverName, dataSourceName string) (*DB, error) {
 		return nil, err
 	}
 	info := parseDSN(dataSourceName)
+	drv := strings.TrimSuffix(info.Driver, "://")
+	if drv == "" {
+		drv = driverName
+	}
 
-	return &DB{connInfo: connInfo{info.DBName, info.User}, db: db}, nil
+	return &DB{connInfo: connInfo{info.DBName, info.User, drv}, db: db}, nil
 }
 
 // OpenDB opens a database.
@@ -143,7 +148,7 @@ func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
 		return nil, err
 	}
 
-	return &Tx{tx: tx}, nil
+	return &Tx{tx: tx, connInfo: db.connInfo}, nil
 }
 
 // Close closes the database, releasing any open resources.
@@ -166,6 +171,14 @@ func (db *DB) Conn(ctx context.Context) (*Conn, error) {
 	return &Conn{conn: conn, connInfo: db.connInfo}, nil
 }
 
+// DriverName returns the driver name Open recorded for DB: the scheme of a
+// URL-style DSN (e.g. "postgres" for "postgres://..."), or else the
+// driverName passed to Open (or "" for a DB constructed via OpenDB, whose
+// driver.Connector carries no name).
+func (db *DB) DriverName() string {
+	return db.driver
+}
+
 // Driver returns the database's underlying driver.
 func (db *DB) Driver(ctx context.Context) driver.Driver {
 	sp, _ := db.startSpan(ctx, "db.Driver", "")
@@ -202,7 +215,7 @@ func (db *DB) Prepare(ctx context.Context, query string) (*Stmt, error) {
 		return nil, err
 	}
 
-	return &Stmt{stmt: stmt}, nil
+	return &Stmt{stmt: stmt, connInfo: db.connInfo}, nil
 }
 
 // Query executes a query that returns rows.
@@ -326,7 +339,7 @@ func (tx *Tx) Prepare(ctx context.Context, query string) (*Stmt, error) {
 		return nil, err
 	}
 
-	return &Stmt{stmt: stmt}, nil
+	return &Stmt{stmt: stmt, connInfo: tx.connInfo}, nil
 }
 
 // Query executes a query that returns rows.
@@ -359,7 +372,7 @@ func (tx *Tx) Rollback(ctx context.Context) error {
 func (tx *Tx) Stmt(ctx context.Context, stmt *Stmt) *Stmt {
 	sp, _ := tx.startSpan(ctx, "tx.Stmt", "")
 	defer trace.SpanComplete(sp, nil)
-	return &Stmt{stmt: tx.tx.StmtContext(ctx, stmt.stmt)}
+	return &Stmt{stmt: tx.tx.StmtContext(ctx, stmt.stmt), connInfo: tx.connInfo}
 }
 
 // Span starts a new SQL child span with specified tags.